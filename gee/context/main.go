@@ -2,19 +2,31 @@ package main
 
 import (
 	"gee/context/mygee"
+	"log"
 	"net/http"
+	"os"
 )
 
 func main() {
 	r := mygee.Default()
+	r.OpenAPIInfo = mygee.OpenAPIInfo{Title: "gee", Version: "1.0.0"}
 	r.GET("/", func(c *mygee.Context) {
 		c.String(http.StatusOK, "Hello Geektutu\n")
-	})
+	}).Summary("Hello")
 	// index out of range for testing Recovery()
 	r.GET("/panic", func(c *mygee.Context) {
 		names := []string{"geektutu"}
 		c.String(http.StatusOK, names[100])
 	})
+	r.GET("/openapi.json", r.OpenAPIHandler())
+
+	// go run . openapi > openapi.json 输出OpenAPI文档而不启动服务
+	if len(os.Args) > 1 && os.Args[1] == "openapi" {
+		if err := r.WriteOpenAPI(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
 	r.Run(":9090")
 }
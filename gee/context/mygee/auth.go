@@ -0,0 +1,108 @@
+package mygee
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+/*
+	认证中间件: Basic认证、API key以及jwt(见jwt.go)
+	认证通过后把调用方的身份保存到c.Keys[AuthUserKey]中,处理函数通过c.Principal()获取
+	认证失败时以401终止,认证通过但无权访问时以403终止,响应体由ErrorHandler统一渲染
+*/
+
+// AuthUserKey 认证通过的调用方身份在Keys中的key
+const AuthUserKey = "mygee.user"
+
+var (
+	// ErrUnauthorized 没有提供凭证或凭证无效
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden 凭证有效但没有访问权限
+	ErrForbidden = errors.New("forbidden")
+)
+
+// Principal 返回认证中间件保存的调用方身份
+// BasicAuth为用户名,APIKey为validator的返回值,JWT为Claims
+func (c *Context) Principal() interface{} {
+	v, _ := c.Get(AuthUserKey)
+	return v
+}
+
+// abortAuth 根据错误类型以401或403终止请求
+func (c *Context) abortAuth(err error, challenge string) {
+	if errors.Is(err, ErrForbidden) {
+		c.AbortWithError(http.StatusForbidden, err).SetType(ErrorTypePublic)
+		return
+	}
+	if challenge != "" {
+		c.SetHeader("WWW-Authenticate", challenge)
+	}
+	if !errors.Is(err, ErrUnauthorized) {
+		err = ErrUnauthorized
+	}
+	c.AbortWithError(http.StatusUnauthorized, err).SetType(ErrorTypePublic)
+}
+
+// Accounts 用户名到密码的映射
+type Accounts map[string]string
+
+func BasicAuth(accounts Accounts) HandlerFunc {
+	return BasicAuthForRealm(accounts, "")
+}
+
+// BasicAuthForRealm
+// realm为空时使用"Authorization Required"
+func BasicAuthForRealm(accounts Accounts, realm string) HandlerFunc {
+	if realm == "" {
+		realm = "Authorization Required"
+	}
+	challenge := "Basic realm=" + strconv.Quote(realm)
+
+	// 保存密码的摘要,比较时长度固定,避免通过耗时推测密码
+	hashes := make(map[string][32]byte, len(accounts))
+	for user, password := range accounts {
+		if user == "" {
+			panic("mygee: basic auth user can not be empty")
+		}
+		hashes[user] = sha256.Sum256([]byte(password))
+	}
+
+	return func(c *Context) {
+		user, password, ok := c.Req.BasicAuth()
+		if !ok {
+			c.abortAuth(ErrUnauthorized, challenge)
+			return
+		}
+		expected, exists := hashes[user]
+		actual := sha256.Sum256([]byte(password))
+		if subtle.ConstantTimeCompare(expected[:], actual[:]) != 1 || !exists {
+			c.abortAuth(ErrUnauthorized, challenge)
+			return
+		}
+		c.Set(AuthUserKey, user)
+		c.Next()
+	}
+}
+
+// APIKey
+// 从header中读取key并交给validator校验,validator返回调用方身份
+// validator返回ErrForbidden(或包装了它的错误)时响应403,其余错误响应401
+func APIKey(header string, validator func(key string) (interface{}, error)) HandlerFunc {
+	return func(c *Context) {
+		key := c.Req.Header.Get(header)
+		if key == "" {
+			c.abortAuth(ErrUnauthorized, "")
+			return
+		}
+		principal, err := validator(key)
+		if err != nil {
+			c.abortAuth(err, "")
+			return
+		}
+		c.Set(AuthUserKey, principal)
+		c.Next()
+	}
+}
@@ -0,0 +1,131 @@
+package mygee
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBasicAuth(t *testing.T) {
+	r := New()
+	r.Use(BasicAuth(Accounts{"gee": "secret"}))
+	r.GET("/me", func(c *Context) {
+		c.String(http.StatusOK, "%s", c.Principal())
+	})
+
+	testCases := []struct {
+		user, password string
+		code           int
+	}{
+		{"gee", "secret", http.StatusOK},
+		{"gee", "wrong", http.StatusUnauthorized},
+		{"other", "secret", http.StatusUnauthorized},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.SetBasicAuth(tc.user, tc.password)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.code {
+			t.Errorf("%s:%s should be %d, got %d", tc.user, tc.password, tc.code, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/me", nil))
+	if w.Header().Get("WWW-Authenticate") != `Basic realm="Authorization Required"` {
+		t.Fatalf("missing basic challenge: %v", w.Header())
+	}
+}
+
+func TestAPIKey(t *testing.T) {
+	r := New()
+	r.Use(APIKey("X-Api-Key", func(key string) (interface{}, error) {
+		switch key {
+		case "good":
+			return "service-a", nil
+		case "readonly":
+			return nil, ErrForbidden
+		}
+		return nil, errors.New("unknown key")
+	}))
+	r.POST("/jobs", func(c *Context) {})
+
+	for key, code := range map[string]int{"good": 200, "readonly": 403, "bad": 401, "": 401} {
+		req := httptest.NewRequest(http.MethodPost, "/jobs", nil)
+		req.Header.Set("X-Api-Key", key)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != code {
+			t.Errorf("key %q should be %d, got %d", key, code, w.Code)
+		}
+	}
+}
+
+func TestJWT(t *testing.T) {
+	secret := []byte("geektutu")
+	now := time.Unix(1700000000, 0)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = time.Now }()
+	conf := JWTConfig{
+		Secret:    secret,
+		Issuer:    "gee",
+		Audience:  "api",
+		ClockSkew: 30 * time.Second,
+		Validate: func(claims Claims) error {
+			if claims["role"] != "admin" {
+				return ErrForbidden
+			}
+			return nil
+		},
+	}
+	r := New()
+	r.Use(JWT(conf))
+	r.GET("/admin", func(c *Context) {
+		c.String(http.StatusOK, "%s", c.Principal().(Claims).Subject())
+	})
+
+	sign := func(claims Claims, alg string) string {
+		token, err := SignJWT(claims, alg, secret)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	valid := func() Claims {
+		return Claims{"sub": "u1", "iss": "gee", "aud": []string{"web", "api"}, "role": "admin",
+			"exp": float64(now.Unix() - 10), "nbf": float64(now.Unix() + 10)}
+	}
+
+	testCases := []struct {
+		name  string
+		token string
+		code  int
+	}{
+		{"HS256 within skew", sign(valid(), "HS256"), http.StatusOK},
+		{"HS384", sign(valid(), "HS384"), http.StatusOK},
+		{"HS512", sign(valid(), "HS512"), http.StatusOK},
+		{"expired", sign(Claims{"iss": "gee", "aud": "api", "role": "admin", "exp": float64(now.Unix() - 60)}, "HS256"), http.StatusUnauthorized},
+		{"not yet valid", sign(Claims{"iss": "gee", "aud": "api", "role": "admin", "nbf": float64(now.Unix() + 60)}, "HS256"), http.StatusUnauthorized},
+		{"wrong issuer", sign(Claims{"iss": "evil", "aud": "api", "role": "admin"}, "HS256"), http.StatusUnauthorized},
+		{"wrong audience", sign(Claims{"iss": "gee", "aud": "web", "role": "admin"}, "HS256"), http.StatusUnauthorized},
+		{"not admin", sign(Claims{"iss": "gee", "aud": "api", "role": "user"}, "HS256"), http.StatusForbidden},
+		{"tampered", sign(valid(), "HS256")[:20] + "x" + sign(valid(), "HS256")[21:], http.StatusUnauthorized},
+		{"alg none", "eyJhbGciOiJub25lIn0.e30.", http.StatusUnauthorized},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.Header.Set("Authorization", "Bearer "+tc.token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.code {
+			t.Errorf("%s: should be %d, got %d %s", tc.name, tc.code, w.Code, w.Body.String())
+		}
+		if tc.code == http.StatusOK && !strings.HasPrefix(w.Body.String(), "u1") {
+			t.Errorf("%s: principal not stored: %q", tc.name, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package mygee
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+	参数绑定: 按结构体字段的tag把请求中的数据解码到结构体中
+	uri   -> 路由中的参数(:name,*name)
+	query -> url中的查询参数
+	form  -> 表单(包括url-encoded和multipart,同时也包含查询参数)
+	json  -> application/json 请求体
+	只有带对应tag的字段才会被绑定,绑定完成后按validate tag做校验
+*/
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	durationType   = reflect.TypeOf(time.Duration(0))
+	fileHeaderType = reflect.TypeOf(&multipart.FileHeader{})
+)
+
+// ShouldBind
+// 依次绑定路由参数、查询参数、表单和请求体,最后做参数校验,出错时只返回错误不写响应
+func (c *Context) ShouldBind(obj interface{}) error {
+	if err := c.bindURI(obj); err != nil {
+		return err
+	}
+	if err := c.bindQuery(obj); err != nil {
+		return err
+	}
+	if err := c.bindForm(obj); err != nil {
+		return err
+	}
+	if err := c.bindJSON(obj); err != nil {
+		return err
+	}
+	return Validate(obj)
+}
+
+// Bind
+// 与ShouldBind相同,出错时记录ErrorTypeBind类型的错误并以400终止,响应中会列出每个校验失败的字段
+func (c *Context) Bind(obj interface{}) error {
+	err := c.ShouldBind(obj)
+	if err != nil {
+		code := http.StatusBadRequest
+		if errors.Is(err, ErrBodyTooLarge) {
+			code = http.StatusRequestEntityTooLarge
+		}
+		c.AbortWithError(code, err).SetType(ErrorTypeBind)
+	}
+	return err
+}
+
+// ShouldBindJSON 只从json请求体绑定
+func (c *Context) ShouldBindJSON(obj interface{}) error {
+	if err := c.bindJSON(obj); err != nil {
+		return err
+	}
+	return Validate(obj)
+}
+
+// ShouldBindQuery 只从查询参数绑定
+func (c *Context) ShouldBindQuery(obj interface{}) error {
+	if err := c.bindQuery(obj); err != nil {
+		return err
+	}
+	return Validate(obj)
+}
+
+// ShouldBindUri 只从路由参数绑定
+func (c *Context) ShouldBindUri(obj interface{}) error {
+	if err := c.bindURI(obj); err != nil {
+		return err
+	}
+	return Validate(obj)
+}
+
+func (c *Context) contentType() string {
+	ct, _, _ := mime.ParseMediaType(c.Req.Header.Get("Content-Type"))
+	return ct
+}
+
+func (c *Context) bindURI(obj interface{}) error {
+	values := make(map[string][]string, len(c.Params))
+	for _, p := range c.Params {
+		values[p.Key] = []string{p.Value}
+	}
+	return mapForm(obj, "uri", values, nil)
+}
+
+func (c *Context) bindQuery(obj interface{}) error {
+	return mapForm(obj, "query", c.Req.URL.Query(), nil)
+}
+
+func (c *Context) bindForm(obj interface{}) error {
+	if c.contentType() == "multipart/form-data" {
+		if err := c.parseMultipartForm(); err != nil {
+			return err
+		}
+		return mapForm(obj, "form", c.Req.Form, c.Req.MultipartForm.File)
+	}
+	if err := c.Req.ParseForm(); err != nil {
+		return err
+	}
+	return mapForm(obj, "form", c.Req.Form, nil)
+}
+
+func (c *Context) bindJSON(obj interface{}) error {
+	if c.contentType() != "application/json" || c.Req.Body == nil {
+		return nil
+	}
+	if err := json.NewDecoder(c.Req.Body).Decode(obj); err != nil && err != io.EOF {
+		return fmt.Errorf("mygee: invalid json body: %w", err)
+	}
+	return nil
+}
+
+// mapForm 把values中的数据按tag写入obj指向的结构体
+func mapForm(obj interface{}, tag string, values map[string][]string, files map[string][]*multipart.FileHeader) error {
+	v := reflect.ValueOf(obj)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("mygee: bind target must be a non-nil pointer to struct, got %T", obj)
+	}
+	return mapStruct(v.Elem(), tag, values, files)
+}
+
+func mapStruct(v reflect.Value, tag string, values map[string][]string, files map[string][]*multipart.FileHeader) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		field := v.Field(i)
+		name := tagName(sf, tag)
+		if name == "-" {
+			continue
+		}
+
+		if name == "" {
+			// 未打tag的内嵌结构体或结构体字段继续向下绑定
+			if field.Kind() == reflect.Struct && field.Type() != timeType {
+				if err := mapStruct(field, tag, values, files); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if fhs, ok := files[name]; ok && len(fhs) > 0 {
+			if field.Type() == fileHeaderType {
+				field.Set(reflect.ValueOf(fhs[0]))
+				continue
+			}
+			if field.Kind() == reflect.Slice && field.Type().Elem() == fileHeaderType {
+				field.Set(reflect.ValueOf(fhs))
+				continue
+			}
+		}
+
+		vals, ok := values[name]
+		if !ok || len(vals) == 0 {
+			continue
+		}
+		if err := setField(field, vals); err != nil {
+			return fmt.Errorf("mygee: bind %s %q: %w", tag, name, err)
+		}
+	}
+	return nil
+}
+
+// tagName 取tag中逗号之前的名字
+func tagName(sf reflect.StructField, tag string) string {
+	name := sf.Tag.Get(tag)
+	if i := strings.IndexByte(name, ','); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+func setField(v reflect.Value, vals []string) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setField(v.Elem(), vals)
+	case reflect.Slice:
+		slice := reflect.MakeSlice(v.Type(), len(vals), len(vals))
+		for i, s := range vals {
+			if err := setValue(slice.Index(i), s); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	default:
+		return setValue(v, vals[0])
+	}
+}
+
+func setValue(v reflect.Value, s string) error {
+	if v.Type() == timeType {
+		if s == "" {
+			return nil
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if v.Type() == durationType {
+		if s == "" {
+			return nil
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		if s == "" {
+			return nil
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s == "" {
+			return nil
+		}
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s == "" {
+			return nil
+		}
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if s == "" {
+			return nil
+		}
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %s", v.Type())
+	}
+	return nil
+}
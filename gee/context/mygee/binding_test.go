@@ -0,0 +1,85 @@
+package mygee
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type bindUser struct {
+	ID    int      `uri:"id"`
+	Page  int      `query:"page" validate:"min=1"`
+	Name  string   `json:"name" validate:"required,min=2,max=8"`
+	Email string   `json:"email" validate:"email"`
+	Role  string   `json:"role" validate:"oneof=admin user"`
+	Tags  []string `json:"tags" validate:"max=2"`
+	Code  string   `json:"code" validate:"regex=^[a-z]{2,3}$"`
+}
+
+func TestShouldBind(t *testing.T) {
+	r := New()
+	var got bindUser
+	var bindErr error
+	r.POST("/user/:id", func(c *Context) {
+		got = bindUser{}
+		bindErr = c.ShouldBind(&got)
+	})
+
+	body := `{"name":"geektutu","email":"a@b.com","role":"admin","tags":["x"],"code":"ab"}`
+	req := httptest.NewRequest(http.MethodPost, "/user/7?page=2", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if bindErr != nil {
+		t.Fatalf("bind failed: %v", bindErr)
+	}
+	if got.ID != 7 || got.Page != 2 || got.Name != "geektutu" || got.Tags[0] != "x" {
+		t.Fatalf("unexpected bind result: %+v", got)
+	}
+
+	body = `{"email":"oops","role":"root","tags":["x","y","z"],"code":"a,b"}`
+	req = httptest.NewRequest(http.MethodPost, "/user/7?page=-1", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var verrs ValidationErrors
+	if !errors.As(bindErr, &verrs) {
+		t.Fatalf("expected ValidationErrors, got %v", bindErr)
+	}
+	fields := make([]string, 0, len(verrs))
+	for _, fe := range verrs {
+		fields = append(fields, fe.Field+"."+fe.Rule)
+	}
+	want := "page.min,name.required,email.email,role.oneof,tags.max,code.regex"
+	if strings.Join(fields, ",") != want {
+		t.Fatalf("unexpected field errors: %v", fields)
+	}
+}
+
+func TestBindForm(t *testing.T) {
+	r := New()
+	var got struct {
+		Name string `form:"name" validate:"required"`
+		Age  uint8  `form:"age"`
+	}
+	var bindErr error
+	r.POST("/form", func(c *Context) {
+		bindErr = c.Bind(&got)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/form?name=gee", strings.NewReader("age=18"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if bindErr != nil || got.Name != "gee" || got.Age != 18 {
+		t.Fatalf("form bind failed: %v %+v", bindErr, got)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/form", strings.NewReader("age=300"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if bindErr == nil || w.Code != http.StatusBadRequest {
+		t.Fatalf("overflowing age should fail with 400, got %d %v", w.Code, bindErr)
+	}
+}
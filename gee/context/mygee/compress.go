@@ -0,0 +1,329 @@
+package mygee
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+/*
+	响应压缩与请求解压
+	Compress根据Accept-Encoding选择gzip或deflate,先缓存响应体的前MinLength个字节再决定是否压缩:
+	响应体太小、已经设置了Content-Encoding、或者Content-Type在排除列表中时原样输出
+	Decompress把Content-Encoding为gzip或deflate的请求体透明地解压,解压后的大小受MaxSize限制
+*/
+
+const (
+	encodingGzip    = "gzip"
+	encodingDeflate = "deflate"
+)
+
+// CompressConfig 响应压缩的配置
+type CompressConfig struct {
+	// Level 压缩级别,默认为gzip.DefaultCompression
+	Level int
+	// MinLength 小于该长度的响应不压缩,默认为1024字节
+	MinLength int
+	// ExcludedPaths 不压缩的路径前缀
+	ExcludedPaths []string
+	// ExcludedContentTypes 不压缩的Content-Type前缀,默认排除图片、音视频和压缩包
+	ExcludedContentTypes []string
+}
+
+var defaultExcludedContentTypes = []string{
+	"image/png", "image/jpeg", "image/gif", "image/webp",
+	"video/", "audio/",
+	"application/zip", "application/gzip", "application/x-gzip", "application/x-bzip2", "application/x-7z-compressed",
+}
+
+func Compress(conf CompressConfig) HandlerFunc {
+	if conf.Level == 0 {
+		conf.Level = gzip.DefaultCompression
+	}
+	if conf.Level < gzip.HuffmanOnly || conf.Level > gzip.BestCompression {
+		panic(fmt.Sprintf("mygee: invalid compression level %d", conf.Level))
+	}
+	if conf.MinLength == 0 {
+		conf.MinLength = 1024
+	}
+	if conf.ExcludedContentTypes == nil {
+		conf.ExcludedContentTypes = defaultExcludedContentTypes
+	}
+
+	var gzipPool, zlibPool sync.Pool
+	gzipPool.New = func() interface{} {
+		w, _ := gzip.NewWriterLevel(io.Discard, conf.Level)
+		return w
+	}
+	zlibPool.New = func() interface{} {
+		w, _ := zlib.NewWriterLevel(io.Discard, conf.Level)
+		return w
+	}
+
+	return func(c *Context) {
+		for _, prefix := range conf.ExcludedPaths {
+			if strings.HasPrefix(c.Path, prefix) {
+				c.Next()
+				return
+			}
+		}
+
+		c.W.Header().Add("Vary", "Accept-Encoding")
+		encoding := negotiateEncoding(c.Req.Header.Get("Accept-Encoding"))
+		if encoding == "" || c.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		cw := &compressWriter{
+			ResponseWriter: c.W,
+			encoding:       encoding,
+			conf:           &conf,
+			pool:           &gzipPool,
+		}
+		if encoding == encodingDeflate {
+			cw.pool = &zlibPool
+		}
+		c.W = cw
+		defer func() {
+			cw.close()
+			c.W = cw.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// negotiateEncoding 按q值选择gzip或deflate,q值相同时优先gzip,都不接受时返回空字符串
+func negotiateEncoding(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		name, q := strings.TrimSpace(part), 1.0
+		if i := strings.IndexByte(name, ';'); i >= 0 {
+			if v := strings.TrimSpace(name[i+1:]); strings.HasPrefix(v, "q=") {
+				if f, err := strconv.ParseFloat(v[2:], 64); err == nil {
+					q = f
+				}
+			}
+			name = strings.TrimSpace(name[:i])
+		}
+		name = strings.ToLower(name)
+		candidates := []string{name}
+		if name == "*" {
+			candidates = []string{encodingGzip, encodingDeflate}
+		}
+		for _, enc := range candidates {
+			if (enc != encodingGzip && enc != encodingDeflate) || q <= 0 {
+				continue
+			}
+			if q > bestQ || (q == bestQ && enc == encodingGzip) {
+				best, bestQ = enc, q
+			}
+		}
+	}
+	return best
+}
+
+// resetWriteCloser gzip.Writer和zlib.Writer的公共方法
+type resetWriteCloser interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// compressWriter 缓存响应体的前MinLength个字节,达到长度后决定是否压缩
+type compressWriter struct {
+	ResponseWriter
+	encoding string
+	conf     *CompressConfig
+	pool     *sync.Pool
+
+	buf     []byte
+	decided bool
+	zw      resetWriteCloser
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.decided {
+		return w.write(data)
+	}
+	w.buf = append(w.buf, data...)
+	if len(w.buf) < w.conf.MinLength {
+		return len(data), nil
+	}
+	if err := w.decide(true); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) write(data []byte) (int, error) {
+	if w.zw != nil {
+		return w.zw.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// decide 决定是否压缩并写出已缓存的数据
+func (w *compressWriter) decide(compress bool) error {
+	w.decided = true
+	h := w.Header()
+	if h.Get("Content-Type") == "" && len(w.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+	status := w.Status()
+	if compress && h.Get("Content-Encoding") == "" && !w.excluded(h.Get("Content-Type")) &&
+		status != http.StatusNoContent && status != http.StatusNotModified && status != http.StatusPartialContent {
+		h.Set("Content-Encoding", w.encoding)
+		h.Del("Content-Length")
+		w.zw = w.pool.Get().(resetWriteCloser)
+		w.zw.Reset(w.ResponseWriter)
+	}
+
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.write(buf)
+	return err
+}
+
+func (w *compressWriter) excluded(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, prefix := range w.conf.ExcludedContentTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Written 缓存中有数据时也认为响应已经开始写出
+func (w *compressWriter) Written() bool {
+	return w.decided || len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+// Flush 流式响应不再等待MinLength,直接开始压缩
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(true)
+	}
+	if w.zw != nil {
+		w.zw.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// WriteHeaderNow 发送响应头之前必须先决定是否压缩,之后的响应体不再等待MinLength
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.decided {
+		w.decide(true)
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.decided = true
+	return w.ResponseWriter.Hijack()
+}
+
+// close 处理链结束后调用: 未达到MinLength的数据原样写出,压缩中的数据写完并归还压缩器
+func (w *compressWriter) close() {
+	if !w.decided {
+		w.decide(false)
+	}
+	if w.zw != nil {
+		w.zw.Close()
+		w.zw.Reset(io.Discard)
+		w.pool.Put(w.zw)
+		w.zw = nil
+	}
+}
+
+const defaultDecompressMaxSize = 32 << 20 // 32 MB
+
+// DecompressConfig 请求解压的配置
+type DecompressConfig struct {
+	// MaxSize 解压后请求体的最大字节数,默认32MB,超过时返回413,防止压缩炸弹
+	MaxSize int64
+}
+
+// Decompress
+// 把Content-Encoding为gzip或deflate的请求体替换为解压后的数据,不支持的编码返回415
+func Decompress() HandlerFunc {
+	return DecompressWithConfig(DecompressConfig{})
+}
+
+func DecompressWithConfig(conf DecompressConfig) HandlerFunc {
+	if conf.MaxSize == 0 {
+		conf.MaxSize = defaultDecompressMaxSize
+	}
+	if conf.MaxSize < 0 {
+		panic("mygee: decompress max size must be positive")
+	}
+	return func(c *Context) {
+		encoding := strings.ToLower(strings.TrimSpace(c.Req.Header.Get("Content-Encoding")))
+		if encoding == "" || encoding == "identity" || c.Req.Body == nil || c.Req.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		var body io.ReadCloser
+		var err error
+		switch encoding {
+		case encodingGzip, "x-gzip":
+			body, err = gzip.NewReader(c.Req.Body)
+		case encodingDeflate:
+			body, err = zlib.NewReader(c.Req.Body)
+		default:
+			c.AbortWithError(http.StatusUnsupportedMediaType,
+				fmt.Errorf("unsupported Content-Encoding %q", encoding)).SetType(ErrorTypePublic)
+			return
+		}
+		if err != nil {
+			c.AbortWithError(http.StatusBadRequest,
+				fmt.Errorf("invalid %s request body: %v", encoding, err)).SetType(ErrorTypePublic)
+			return
+		}
+
+		// 解压后的大小与Content-Length无关,与BodyLimit一样在读取超过限制时返回ErrBodyTooLarge
+		limited := &limitedBody{
+			ReadCloser: &decompressBody{ReadCloser: body, orig: c.Req.Body},
+			remaining:  conf.MaxSize,
+		}
+		c.Req.Body = limited
+		c.Req.Header.Del("Content-Encoding")
+		c.Req.Header.Del("Content-Length")
+		c.Req.ContentLength = -1
+		c.Next()
+
+		if limited.exceeded && !c.W.Written() {
+			c.AbortWithError(http.StatusRequestEntityTooLarge, ErrBodyTooLarge).SetType(ErrorTypePublic)
+		}
+	}
+}
+
+// decompressBody 关闭时同时关闭原始请求体
+type decompressBody struct {
+	io.ReadCloser
+	orig io.ReadCloser
+}
+
+func (b *decompressBody) Close() error {
+	err := b.ReadCloser.Close()
+	if origErr := b.orig.Close(); err == nil {
+		err = origErr
+	}
+	return err
+}
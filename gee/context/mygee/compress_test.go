@@ -0,0 +1,143 @@
+package mygee
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	testCases := map[string]string{
+		"":                       "",
+		"gzip, deflate, br":      "gzip",
+		"deflate":                "deflate",
+		"gzip;q=0.5, deflate":    "deflate",
+		"*":                      "gzip",
+		"br, gzip;q=0":           "",
+		"identity, deflate;q=.1": "deflate",
+	}
+	for header, want := range testCases {
+		if got := negotiateEncoding(header); got != want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat("geektutu ", 500)
+	r := New()
+	r.Use(Compress(CompressConfig{ExcludedPaths: []string{"/raw"}}))
+	r.GET("/large", func(c *Context) { c.String(http.StatusOK, large) })
+	r.GET("/small", func(c *Context) { c.String(http.StatusOK, "tiny") })
+	r.GET("/raw/large", func(c *Context) { c.String(http.StatusOK, large) })
+	r.GET("/png", func(c *Context) {
+		c.SetHeader("Content-Type", "image/png")
+		c.Data(http.StatusOK, []byte(large))
+	})
+
+	get := func(path, accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", accept)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/large", "gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("large response should be gzipped: %v", w.Header())
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(zr); string(data) != large {
+		t.Fatalf("gzip body mismatch")
+	}
+
+	w = get("/large", "deflate")
+	zlr, err := zlib.NewReader(w.Body)
+	if err != nil {
+		t.Fatalf("deflate body is not zlib: %v", err)
+	}
+	if data, _ := io.ReadAll(zlr); string(data) != large {
+		t.Fatalf("deflate body mismatch")
+	}
+
+	// 先发送响应头再写响应体时,压缩的决定要在响应头发送之前做出
+	r.GET("/header-first", func(c *Context) {
+		c.Status(http.StatusOK)
+		c.W.WriteHeaderNow()
+		c.W.Write([]byte(large))
+	})
+	w = get("/header-first", "gzip")
+	if w.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("body written after WriteHeaderNow should be gzipped with Content-Encoding: %v", w.Header())
+	}
+	if zr, err := gzip.NewReader(w.Body); err != nil {
+		t.Fatalf("body is not gzip: %v", err)
+	} else if data, _ := io.ReadAll(zr); string(data) != large {
+		t.Fatalf("gzip body mismatch")
+	}
+
+	for _, path := range []string{"/small", "/raw/large", "/png"} {
+		if w := get(path, "gzip"); w.Header().Get("Content-Encoding") != "" || w.Body.Len() < 4 {
+			t.Errorf("%s should not be compressed: %v", path, w.Header())
+		}
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	r := New()
+	r.Use(Decompress())
+	r.POST("/echo", func(c *Context) {
+		data, _ := io.ReadAll(c.Req.Body)
+		c.Data(http.StatusOK, data)
+	})
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte("hello gee"))
+	zw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/echo", &buf)
+	req.Header.Set("Content-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Body.String() != "hello gee" {
+		t.Fatalf("decompress failed: %q", w.Body.String())
+	}
+
+	// 压缩后很小但解压后超过限制的请求体
+	small := New()
+	small.Use(DecompressWithConfig(DecompressConfig{MaxSize: 1024}))
+	small.POST("/echo", func(c *Context) {
+		if _, err := io.ReadAll(c.Req.Body); err != ErrBodyTooLarge {
+			t.Errorf("reading a gzip bomb should fail with ErrBodyTooLarge, got %v", err)
+		}
+	})
+	buf.Reset()
+	zw.Reset(&buf)
+	zw.Write(make([]byte, 1<<20))
+	zw.Close()
+	req = httptest.NewRequest(http.MethodPost, "/echo", &buf)
+	req.Header.Set("Content-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	small.ServeHTTP(w, req)
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized decompressed body should be 413, got %d", w.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("not gzip"))
+	req.Header.Set("Content-Encoding", "gzip")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid gzip body should be 400, got %d", w.Code)
+	}
+}
@@ -3,7 +3,11 @@ package mygee
 import (
 	"encoding/json"
 	"fmt"
+	"math"
+	"net"
 	"net/http"
+	"strings"
+	"sync"
 )
 
 /*
@@ -12,30 +16,100 @@ import (
 */
 type H map[string]interface{}
 
+// Param 路由中的一个参数
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params 按路由中出现的顺序保存的参数,用切片代替map以便在请求之间复用
+type Params []Param
+
+// Get 返回名为name的参数值
+func (ps Params) Get(name string) (string, bool) {
+	for _, p := range ps {
+		if p.Key == name {
+			return p.Value, true
+		}
+	}
+	return "", false
+}
+
+// ByName 返回名为name的参数值,不存在时返回空字符串
+func (ps Params) ByName(name string) string {
+	v, _ := ps.Get(name)
+	return v
+}
+
+// Context
+// Engine通过sync.Pool复用Context,处理函数返回后不能再使用c,需要在其他goroutine中使用时先调用Copy
 type Context struct {
-	W          http.ResponseWriter
-	Req        *http.Request
-	Path       string
-	Method     string
-	Params     map[string]string
-	StatusCode int
+	W        ResponseWriter // 记录了状态码和写出字节数的ResponseWriter
+	Req      *http.Request
+	Path     string
+	FullPath string // 匹配到的路由规则,如/user/:id,未匹配到时为空
+	Method   string
+	Params   Params
 
 	handlers []HandlerFunc
 	index    int
 
-	e *Engine
+	// Errors 处理过程中通过c.Error记录的错误
+	Errors errorMsgs
+
+	// Keys 中间件与处理函数之间传递数据,通过Set/Get访问
+	Keys map[string]interface{}
+	mu   sync.RWMutex
+
+	writermem responseWriter
+	e         *Engine
 }
 
 func NewContext(w http.ResponseWriter, req *http.Request) *Context {
-	return &Context{
-		W:      w,
-		Req:    req,
-		Path:   req.URL.Path,
-		Method: req.Method,
-		index:  -1,
+	c := &Context{}
+	c.reset(w, req)
+	return c
+}
+
+// reset 重置从池中取出的Context,保留Params的底层数组以便复用
+func (c *Context) reset(w http.ResponseWriter, req *http.Request) {
+	c.writermem.reset(w)
+	c.W = &c.writermem
+	c.Req = req
+	c.Path = req.URL.Path
+	c.FullPath = ""
+	c.Method = req.Method
+	c.Params = c.Params[:0]
+	c.Errors = c.Errors[:0]
+	c.handlers = nil
+	c.index = -1
+	c.resetKeys()
+}
+
+// Copy
+// 返回可以在处理函数返回后继续安全使用的副本,副本不能再调用Next
+// 副本的W与原来的响应分离,只保留复制时的状态码和大小,写入时返回错误
+func (c *Context) Copy() *Context {
+	cp := &Context{
+		Req:      c.Req,
+		Path:     c.Path,
+		FullPath: c.FullPath,
+		Method:   c.Method,
+		Params:   make(Params, len(c.Params)),
+		index:    abortIndex,
+		Keys:     c.copyKeys(),
+		e:        c.e,
 	}
+	copy(cp.Params, c.Params)
+	cp.writermem.reset(detachedWriter{header: c.W.Header().Clone()})
+	cp.writermem.status, cp.writermem.size = c.W.Status(), c.W.Size()
+	cp.W = &cp.writermem
+	return cp
 }
 
+// abortIndex 调用Abort后index被设为该值,Next中的循环随即结束
+const abortIndex = math.MaxInt32 >> 1
+
 func (c *Context) Next() {
 	c.index++
 	for ; c.index < len(c.handlers); c.index++ {
@@ -43,8 +117,30 @@ func (c *Context) Next() {
 	}
 }
 
+// Abort
+// 阻止处理链中后续的处理函数执行,不影响当前处理函数的剩余逻辑
+// 例如鉴权中间件在校验失败时调用Abort并直接返回
+func (c *Context) Abort() {
+	c.index = abortIndex
+}
+
+func (c *Context) IsAborted() bool {
+	return c.index >= abortIndex
+}
+
+func (c *Context) AbortWithStatus(code int) {
+	c.Abort()
+	c.Status(code)
+}
+
+func (c *Context) AbortWithStatusJSON(code int, obj interface{}) {
+	c.Abort()
+	c.JSON(code, obj)
+}
+
+// Status
+// 只记录状态码,响应头在写出响应体或处理链结束时发送
 func (c *Context) Status(code int) {
-	c.StatusCode = code
 	c.W.WriteHeader(code)
 }
 
@@ -53,10 +149,7 @@ func (c *Context) SetHeader(key string, val string) {
 }
 
 func (c *Context) Param(key string) string {
-	if _, ok := c.Params[key]; ok {
-		return c.Params[key]
-	}
-	return ""
+	return c.Params.ByName(key)
 }
 func (c *Context) PostForm(key string) string {
 	return c.Req.FormValue(key)
@@ -66,6 +159,29 @@ func (c *Context) Query(key string) string {
 	return c.Req.URL.Query().Get(key)
 }
 
+// ClientIP
+// 返回客户端ip,Engine.ForwardedByClientIP打开时优先使用代理设置的请求头
+func (c *Context) ClientIP() string {
+	if c.e != nil && c.e.ForwardedByClientIP {
+		if fwd := c.Req.Header.Get("X-Forwarded-For"); fwd != "" {
+			if i := strings.IndexByte(fwd, ','); i >= 0 {
+				fwd = fwd[:i]
+			}
+			if ip := strings.TrimSpace(fwd); ip != "" {
+				return ip
+			}
+		}
+		if ip := strings.TrimSpace(c.Req.Header.Get("X-Real-IP")); ip != "" {
+			return ip
+		}
+	}
+	host, _, err := net.SplitHostPort(strings.TrimSpace(c.Req.RemoteAddr))
+	if err != nil {
+		return c.Req.RemoteAddr
+	}
+	return host
+}
+
 func (c *Context) String(code int, format string, value ...interface{}) {
 	c.SetHeader("Content-Type", "text/plain")
 	c.Status(code)
@@ -87,11 +203,3 @@ func (c *Context) JSON(code int, obj interface{}) {
 	}
 
 }
-
-func (c *Context) HTML(code int, name string, data interface{}) {
-	c.SetHeader("Content-Type", "text/html")
-	c.Status(code)
-	if err := c.e.htmlTemplates.ExecuteTemplate(c.W, name, data); err != nil {
-		http.Error(c.W, err.Error(), 500)
-	}
-}
@@ -0,0 +1,91 @@
+package mygee
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAbort(t *testing.T) {
+	r := New()
+	reached := false
+	r.Use(func(c *Context) {
+		if c.Req.Header.Get("Authorization") == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, H{"error": "unauthorized"})
+			return
+		}
+		c.Set("user", "geektutu")
+		c.Next()
+	})
+	r.GET("/me", func(c *Context) {
+		reached = true
+		c.String(http.StatusOK, "%s", c.MustGet("user"))
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/me", nil))
+	if w.Code != http.StatusUnauthorized || reached {
+		t.Fatalf("aborted request reached handler: %d %v", w.Code, reached)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "token")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.String() != "geektutu" {
+		t.Fatalf("authorized request failed: %d %q", w.Code, w.Body.String())
+	}
+}
+
+type ctxKey struct{}
+
+func TestContextDelegation(t *testing.T) {
+	r := New()
+	var got interface{}
+	var doneErr error
+	r.GET("/", func(c *Context) {
+		c.Set("user", "gee")
+		var ctx context.Context = c
+		got = ctx.Value(ctxKey{})
+		if ctx.Value("user") != "gee" {
+			t.Errorf("Value should look up Keys first")
+		}
+		<-ctx.Done()
+		doneErr = ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, 1))
+	cancel()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	if got != 1 || doneErr != context.Canceled {
+		t.Fatalf("context delegation failed: %v %v", got, doneErr)
+	}
+}
+
+func TestCopyDetachedWriter(t *testing.T) {
+	r := New()
+	var cp *Context
+	r.GET("/", func(c *Context) {
+		c.Status(http.StatusCreated)
+		cp = c.Copy()
+		c.String(http.StatusCreated, "first")
+	})
+	r.GET("/second", func(c *Context) {
+		c.String(http.StatusOK, "second")
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if cp.W.Status() != http.StatusCreated {
+		t.Fatalf("copy should keep the status, got %d", cp.W.Status())
+	}
+	// 原来的Context已经被下一个请求复用,副本不能写到这个请求的响应里
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/second", nil))
+	if _, err := cp.W.Write([]byte("late")); err == nil {
+		t.Fatal("writing from a copy should fail")
+	}
+	if w.Body.String() != "second" {
+		t.Fatalf("copy wrote into another response: %q", w.Body.String())
+	}
+}
@@ -0,0 +1,42 @@
+package mygee
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// CookieOptions 写入cookie时的属性
+type CookieOptions struct {
+	Path     string // 为空时为"/"
+	Domain   string
+	MaxAge   int // 秒,为0时是会话cookie,小于0时删除cookie
+	Secure   bool
+	HttpOnly bool
+	SameSite http.SameSite
+}
+
+// Cookie 返回请求中名为name的cookie的值,值会做url解码
+func (c *Context) Cookie(name string) (string, error) {
+	cookie, err := c.Req.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	return url.QueryUnescape(cookie.Value)
+}
+
+// SetCookie 在响应中写入cookie,值会做url编码
+func (c *Context) SetCookie(name string, value string, opts CookieOptions) {
+	if opts.Path == "" {
+		opts.Path = "/"
+	}
+	http.SetCookie(c.W, &http.Cookie{
+		Name:     name,
+		Value:    url.QueryEscape(value),
+		Path:     opts.Path,
+		Domain:   opts.Domain,
+		MaxAge:   opts.MaxAge,
+		Secure:   opts.Secure,
+		HttpOnly: opts.HttpOnly,
+		SameSite: opts.SameSite,
+	})
+}
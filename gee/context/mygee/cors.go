@@ -0,0 +1,132 @@
+package mygee
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+	跨域资源共享(CORS)
+	预检请求(带Access-Control-Request-Method的OPTIONS)由中间件直接应答,不会到达路由的处理函数
+	中间件可以挂在分组上,此时自动应答的OPTIONS和405也会经过该分组的中间件,所以不同分组可以使用不同的策略
+*/
+
+// CORSConfig 跨域配置
+type CORSConfig struct {
+	// AllowOrigins 允许的来源,支持完整匹配、"*"以及形如"https://*.example.com"的通配
+	AllowOrigins []string
+	// AllowOriginFunc 自定义来源判断,与AllowOrigins任一满足即允许
+	AllowOriginFunc func(origin string) bool
+	// AllowMethods 预检时返回的允许方法,默认为GET,POST,PUT,PATCH,DELETE,HEAD
+	AllowMethods []string
+	// AllowHeaders 预检时返回的允许请求头,为空时原样返回预检请求中的Access-Control-Request-Headers
+	AllowHeaders []string
+	// ExposeHeaders 允许浏览器读取的响应头
+	ExposeHeaders []string
+	// AllowCredentials 是否允许携带cookie等凭证,此时不会返回"*"而是返回请求的来源
+	AllowCredentials bool
+	// MaxAge 预检结果的缓存时间
+	MaxAge time.Duration
+}
+
+var defaultCORSMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead,
+}
+
+func CORS(conf CORSConfig) HandlerFunc {
+	methods := conf.AllowMethods
+	if len(methods) == 0 {
+		methods = defaultCORSMethods
+	}
+	allowMethods := strings.ToUpper(strings.Join(methods, ", "))
+	allowHeaders := strings.Join(conf.AllowHeaders, ", ")
+	exposeHeaders := strings.Join(conf.ExposeHeaders, ", ")
+	maxAge := ""
+	if conf.MaxAge > 0 {
+		maxAge = strconv.FormatInt(int64(conf.MaxAge/time.Second), 10)
+	}
+
+	allowAll := false
+	exact := make(map[string]bool)
+	var wildcards [][2]string
+	for _, origin := range conf.AllowOrigins {
+		switch i := strings.IndexByte(origin, '*'); {
+		case origin == "*":
+			allowAll = true
+		case i >= 0:
+			wildcards = append(wildcards, [2]string{strings.ToLower(origin[:i]), strings.ToLower(origin[i+1:])})
+		default:
+			exact[strings.ToLower(origin)] = true
+		}
+	}
+	allowOrigin := func(origin string) bool {
+		if allowAll {
+			return true
+		}
+		lower := strings.ToLower(origin)
+		if exact[lower] {
+			return true
+		}
+		for _, w := range wildcards {
+			if len(lower) > len(w[0])+len(w[1]) && strings.HasPrefix(lower, w[0]) && strings.HasSuffix(lower, w[1]) {
+				return true
+			}
+		}
+		return conf.AllowOriginFunc != nil && conf.AllowOriginFunc(origin)
+	}
+
+	return func(c *Context) {
+		origin := c.Req.Header.Get("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		h := c.W.Header()
+		h.Add("Vary", "Origin")
+		preflight := c.Method == http.MethodOptions && c.Req.Header.Get("Access-Control-Request-Method") != ""
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if !allowOrigin(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if allowAll && !conf.AllowCredentials {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+		if conf.AllowCredentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if exposeHeaders != "" {
+				h.Set("Access-Control-Expose-Headers", exposeHeaders)
+			}
+			c.Next()
+			return
+		}
+
+		h.Set("Access-Control-Allow-Methods", allowMethods)
+		if allowHeaders != "" {
+			h.Set("Access-Control-Allow-Headers", allowHeaders)
+		} else if reqHeaders := c.Req.Header.Get("Access-Control-Request-Headers"); reqHeaders != "" {
+			h.Set("Access-Control-Allow-Headers", reqHeaders)
+		}
+		if maxAge != "" {
+			h.Set("Access-Control-Max-Age", maxAge)
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
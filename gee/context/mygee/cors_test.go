@@ -0,0 +1,66 @@
+package mygee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCORSGroups(t *testing.T) {
+	r := New()
+	reached := false
+	api := r.Group("/api")
+	api.Use(CORS(CORSConfig{
+		AllowOrigins:     []string{"https://app.example.com", "https://*.example.org"},
+		AllowOriginFunc:  func(origin string) bool { return strings.HasSuffix(origin, ".test") },
+		AllowHeaders:     []string{"Authorization"},
+		ExposeHeaders:    []string{"X-Total"},
+		AllowCredentials: true,
+		MaxAge:           time.Hour,
+	}))
+	api.POST("/users", func(c *Context) { reached = true })
+	api.OPTIONS("/explicit", func(c *Context) { reached = true })
+	public := r.Group("/public")
+	public.Use(CORS(CORSConfig{AllowOrigins: []string{"*"}}))
+	public.GET("/info", func(c *Context) {})
+
+	preflight := func(path, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodOptions, path, nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", "POST")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, origin := range []string{"https://app.example.com", "https://a.example.org", "http://dev.test"} {
+		w := preflight("/api/users", origin)
+		h := w.Header()
+		if w.Code != http.StatusNoContent || h.Get("Access-Control-Allow-Origin") != origin ||
+			h.Get("Access-Control-Allow-Credentials") != "true" || h.Get("Access-Control-Max-Age") != "3600" ||
+			h.Get("Access-Control-Allow-Headers") != "Authorization" {
+			t.Errorf("preflight from %s failed: %d %v", origin, w.Code, h)
+		}
+	}
+	if w := preflight("/api/users", "https://evil.com"); w.Code != http.StatusForbidden {
+		t.Errorf("preflight from disallowed origin should be 403, got %d", w.Code)
+	}
+	if w := preflight("/api/explicit", "https://app.example.com"); w.Code != http.StatusNoContent || reached {
+		t.Errorf("preflight should not reach the route handler")
+	}
+
+	w := preflight("/public/info", "https://anything.com")
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("public policy should allow any origin, got %v", w.Header())
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/users", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if !reached || w.Header().Get("Access-Control-Expose-Headers") != "X-Total" {
+		t.Errorf("simple request failed: %v", w.Header())
+	}
+}
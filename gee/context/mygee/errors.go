@@ -0,0 +1,181 @@
+package mygee
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+/*
+	统一的错误处理
+	处理函数通过c.Error记录错误,通过c.AbortWithError记录错误并设置待写出的状态码
+	ErrorHandler中间件在处理链结束后把记录的错误渲染成RFC 7807格式的problem+json响应
+	私有错误(默认类型)只记录不对外展示,公开错误和参数绑定错误的信息会写到响应的detail中
+*/
+
+type ErrorType uint8
+
+const (
+	ErrorTypePrivate ErrorType = 1 << iota // 内部错误,不在响应中展示
+	ErrorTypePublic                        // 可以展示给调用方的错误
+	ErrorTypeBind                          // 参数绑定或校验失败
+
+	ErrorTypeAny ErrorType = 1<<8 - 1
+)
+
+const MIMEProblemJSON = "application/problem+json"
+
+// Error 记录在Context上的错误
+type Error struct {
+	Err  error
+	Type ErrorType
+	Meta interface{}
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) SetType(t ErrorType) *Error {
+	e.Type = t
+	return e
+}
+
+func (e *Error) SetMeta(meta interface{}) *Error {
+	e.Meta = meta
+	return e
+}
+
+func (e *Error) IsType(t ErrorType) bool {
+	return e.Type&t > 0
+}
+
+// errorMsgs 一个请求中记录的所有错误
+type errorMsgs []*Error
+
+// ByType 返回指定类型的错误
+func (es errorMsgs) ByType(t ErrorType) errorMsgs {
+	res := make(errorMsgs, 0)
+	for _, e := range es {
+		if e.IsType(t) {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// Last 返回最后一个错误,没有错误时返回nil
+func (es errorMsgs) Last() *Error {
+	if len(es) == 0 {
+		return nil
+	}
+	return es[len(es)-1]
+}
+
+func (es errorMsgs) String() string {
+	msgs := make([]string, 0, len(es))
+	for i, e := range es {
+		msgs = append(msgs, fmt.Sprintf("Error #%02d: %s", i+1, e.Err))
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Error
+// 记录一个错误,默认为私有错误,可以通过返回值修改类型和附加信息
+func (c *Context) Error(err error) *Error {
+	if err == nil {
+		panic("mygee: err is nil")
+	}
+	var e *Error
+	if !errors.As(err, &e) {
+		e = &Error{Err: err, Type: ErrorTypePrivate}
+	}
+	c.Errors = append(c.Errors, e)
+	return e
+}
+
+// AbortWithError
+// 记录错误并终止处理链,状态码不会立即写出,交给ErrorHandler或Engine在处理链结束后统一写出
+func (c *Context) AbortWithError(code int, err error) *Error {
+	c.Abort()
+	c.Status(code)
+	return c.Error(err)
+}
+
+// Problem RFC 7807中定义的错误响应格式
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"` // 参数校验失败的字段
+}
+
+// Problem 按problem+json格式写出错误响应
+func (c *Context) Problem(p Problem) {
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	c.SetHeader("Content-Type", MIMEProblemJSON)
+	c.Status(p.Status)
+	if err := json.NewEncoder(c.W).Encode(p); err != nil {
+		log.Printf("mygee: write problem response: %v", err)
+	}
+}
+
+// ErrorHandler
+// 处理链结束后,如果记录了错误且响应还没有写出,就把错误渲染为problem+json
+// 状态码取AbortWithError设置的值,没有设置时使用500
+func ErrorHandler() HandlerFunc {
+	return func(c *Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.W.Written() {
+			return
+		}
+		c.Problem(c.problem())
+	}
+}
+
+func (c *Context) problem() Problem {
+	code := c.W.Status()
+	if code < http.StatusBadRequest {
+		code = http.StatusInternalServerError
+	}
+	p := Problem{Status: code, Instance: c.Req.URL.Path}
+
+	details := make([]string, 0)
+	for _, e := range c.Errors.ByType(ErrorTypePublic | ErrorTypeBind) {
+		var verrs ValidationErrors
+		if errors.As(e.Err, &verrs) {
+			p.Errors = append(p.Errors, verrs...)
+		}
+		details = append(details, e.Error())
+	}
+	p.Detail = strings.Join(details, "; ")
+	return p
+}
+
+// writePending 处理链结束后,如果响应头还没有发送,在这里补写
+// 通过AbortWithError设置了错误状态码但没有ErrorHandler处理时,使用problem+json作为响应体
+func (c *Context) writePending() {
+	if c.W.Written() {
+		return
+	}
+	if len(c.Errors) > 0 && c.W.Status() >= http.StatusBadRequest {
+		c.Problem(c.problem())
+		return
+	}
+	c.W.WriteHeaderNow()
+}
@@ -0,0 +1,84 @@
+package mygee
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestErrorHandler(t *testing.T) {
+	r := New()
+	r.Use(ErrorHandler(), Recovery())
+	r.GET("/public", func(c *Context) {
+		c.AbortWithError(http.StatusConflict, errors.New("user already exists")).SetType(ErrorTypePublic)
+	})
+	r.GET("/private", func(c *Context) {
+		c.Error(errors.New("db password is wrong"))
+	})
+	r.GET("/panic", func(c *Context) {
+		panic("boom")
+	})
+	r.POST("/bind", func(c *Context) {
+		var obj struct {
+			Name string `json:"name" validate:"required"`
+		}
+		c.Bind(&obj)
+	})
+
+	testCases := []struct {
+		method, path string
+		code         int
+		detail       string
+	}{
+		{http.MethodGet, "/public", http.StatusConflict, "user already exists"},
+		{http.MethodGet, "/private", http.StatusInternalServerError, ""},
+		{http.MethodGet, "/panic", http.StatusInternalServerError, ""},
+		{http.MethodPost, "/bind", http.StatusBadRequest, "mygee: validation failed: name: is required"},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		var p Problem
+		if err := json.Unmarshal(w.Body.Bytes(), &p); err != nil {
+			t.Fatalf("%s: invalid problem body %q", tc.path, w.Body.String())
+		}
+		if w.Code != tc.code || p.Status != tc.code || p.Detail != tc.detail || p.Instance != tc.path {
+			t.Errorf("%s: unexpected problem %d %+v", tc.path, w.Code, p)
+		}
+		if ct := w.Header().Get("Content-Type"); ct != MIMEProblemJSON {
+			t.Errorf("%s: unexpected Content-Type %s", tc.path, ct)
+		}
+	}
+}
+
+func TestNoRoute(t *testing.T) {
+	r := New()
+	r.GET("/user", func(c *Context) {})
+	r.NoRoute(func(c *Context) {
+		c.Set("missing", true)
+		c.Next()
+	}, func(c *Context) {
+		c.JSON(http.StatusNotFound, H{"missing": c.GetBool("missing")})
+	})
+	r.NoMethod(func(c *Context) {
+		c.JSON(http.StatusMethodNotAllowed, H{"allow": c.W.Header().Get("Allow")})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/none", nil))
+	if w.Code != http.StatusNotFound || w.Body.String() != "{\"missing\":true}\n" {
+		t.Fatalf("NoRoute failed: %d %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/user", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Body.String() != "{\"allow\":\"GET, OPTIONS\"}\n" {
+		t.Fatalf("NoMethod failed: %d %s", w.Code, w.Body.String())
+	}
+}
@@ -1,141 +1,201 @@
 package mygee
 
 import (
-	"html/template"
-	"log"
 	"net/http"
-	"path"
-	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type HandlerFunc func(c *Context)
 
+// anyMethods Any注册时覆盖的请求方法
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodHead, http.MethodOptions,
+}
+
 type RouterGroup struct {
 	prefix      string
 	parent      *RouterGroup
 	middlewares []HandlerFunc
 	engine      *Engine
+
+	// 路径匹配但方法不匹配时使用的处理链,由router.rebuild组装
+	noMethod   []HandlerFunc
+	autoOption []HandlerFunc
 }
 
 // Engine
 // Engine和RouterGroup是双向对应关系
 type Engine struct {
-	*RouterGroup  //通过将group设置为属性从而实现继承的关系,进而调用该属性对应的方法
-	router        *router
-	routerGroups  []*RouterGroup
-	htmlTemplates *template.Template
-	funcMap       template.FuncMap
+	*RouterGroup //通过将group设置为属性从而实现继承的关系,进而调用该属性对应的方法
+	router       *router
+	routerGroups []*RouterGroup
+	html         *htmlRender
+
+	// ForwardedByClientIP 为true时ClientIP优先从X-Forwarded-For和X-Real-IP请求头中获取
+	// 只有服务部署在可信的反向代理之后时才应该打开
+	ForwardedByClientIP bool
+
+	// TemplateReload 开发模式,模板文件变化后在下一次渲染前自动重新解析
+	TemplateReload bool
+
+	// MaxMultipartMemory 解析multipart表单时保存在内存中的最大字节数,超过的部分写入临时文件
+	MaxMultipartMemory int64
+
+	// OpenAPIInfo OpenAPI文档中的标题、版本等信息
+	OpenAPIInfo OpenAPIInfo
+
+	// 底层http.Server的超时时间,为0表示不限制
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+
+	mu         sync.Mutex
+	servers    []*http.Server
+	onShutdown []func()
+	inflight   int32 // 正在处理的请求数,通过atomic访问
+	draining   int32 // Shutdown开始等待请求结束后为1
+	drained    chan struct{}
+	drainOnce  sync.Once
+	closing    bool
+	closed     chan struct{}
+
+	pool    sync.Pool // 复用Context
+	metrics *metricsRegistry
 }
 
 func New() *Engine {
-	engine := &Engine{router: newRouter()}
+	engine := &Engine{
+		router:             newRouter(),
+		closed:             make(chan struct{}),
+		drained:            make(chan struct{}),
+		metrics:            newMetricsRegistry(),
+		html:               &htmlRender{},
+		MaxMultipartMemory: defaultMultipartMemory,
+	}
 
 	engine.RouterGroup = &RouterGroup{engine: engine}
 	engine.routerGroups = []*RouterGroup{engine.RouterGroup}
+	engine.router.rebuild(engine.routerGroups)
+	engine.pool.New = func() interface{} {
+		return &Context{e: engine}
+	}
 	return engine
 }
 
-func (e *Engine) SetFuncMap(funcMap template.FuncMap) {
-	e.funcMap = funcMap
-}
-
 // Default
 // 默认引擎支持日志打印和错误处理
 func Default() *Engine {
 	engine := New()
-	engine.Use(Logger(), Recovery())
+	engine.Use(RequestID(), Logger(), ErrorHandler(), Recovery())
 	return engine
 }
 
-// LoadHtmlGlob
-// 加载所有静态文件到模板中
-func (e *Engine) LoadHtmlGlob(pattern string) {
-	e.htmlTemplates = template.Must(template.New("").Funcs(e.funcMap).ParseGlob(pattern))
-}
-
+// Use
+// 中间件变化后重新组装已注册路由的处理链,所以注册路由之后再Use也会生效
 func (g *RouterGroup) Use(middlewares ...HandlerFunc) {
 	g.middlewares = append(g.middlewares, middlewares...)
+	g.engine.router.rebuild(g.engine.routerGroups)
 }
 
+// Group
+// 子分组只保存自己的中间件,父分组的中间件在组装处理链时沿parent向上收集
 func (g *RouterGroup) Group(prefix string) *RouterGroup {
 	engine := g.engine
 
 	newGroup := &RouterGroup{
-		prefix:      g.prefix + prefix,
-		middlewares: g.middlewares,
-		parent:      g,
-		engine:      engine,
+		prefix: g.prefix + prefix,
+		parent: g,
+		engine: engine,
 	}
 	engine.routerGroups = append(engine.routerGroups, newGroup)
+	engine.router.rebuild(engine.routerGroups)
 	return newGroup
 }
 
-func (g *RouterGroup) addRoute(method string, pattern string, handler HandlerFunc) {
-	g.engine.router.addRoute(method, pattern, handler)
-}
-func (g *RouterGroup) GET(pattern string, handler HandlerFunc) {
-	g.addRoute("GET", pattern, handler)
-}
+// combineHandlers 按从外到内的顺序收集分组链上的中间件,最后追加处理函数
+func (g *RouterGroup) combineHandlers(handlers ...HandlerFunc) []HandlerFunc {
+	var groups []*RouterGroup
+	size := len(handlers)
+	for group := g; group != nil; group = group.parent {
+		groups = append(groups, group)
+		size += len(group.middlewares)
+	}
 
-func (g *RouterGroup) POST(pattern string, handler HandlerFunc) {
-	g.addRoute("POST", pattern, handler)
+	res := make([]HandlerFunc, 0, size)
+	for i := len(groups) - 1; i >= 0; i-- {
+		res = append(res, groups[i].middlewares...)
+	}
+	return append(res, handlers...)
 }
 
-func (g *RouterGroup) createStaticHandler(relativePath string, fs http.FileSystem) HandlerFunc {
-	absolutePath := g.prefix + relativePath
-
-	// 把请求中url的absolutePath部分去掉
-	fileServer := http.StripPrefix(absolutePath, http.FileServer(fs))
+// NoRoute
+// 设置没有匹配到路由时的处理链,Engine上的中间件仍然会先执行
+func (e *Engine) NoRoute(handlers ...HandlerFunc) {
+	e.router.noRouteHandlers = handlers
+	e.router.rebuild(e.routerGroups)
+}
 
-	return func(c *Context) {
-		file := c.Param("filepath")
+// NoMethod
+// 设置路径存在但请求方法不匹配时的处理链,Allow响应头已经由框架设置好
+func (e *Engine) NoMethod(handlers ...HandlerFunc) {
+	e.router.noMethodHandlers = handlers
+	e.router.rebuild(e.routerGroups)
+}
 
-		if _, err := fs.Open(file); err != nil {
-			c.Status(http.StatusNotFound)
-			return
-		}
-		fileServer.ServeHTTP(c.W, c.Req)
-	}
+func (g *RouterGroup) addRoute(method string, pattern string, handler HandlerFunc) *Route {
+	n := g.engine.router.addRoute(method, g.prefix+pattern, g, handler)
+	return &Route{method: method, n: n, r: g.engine.router}
+}
 
+func (g *RouterGroup) GET(pattern string, handler HandlerFunc) *Route {
+	return g.addRoute("GET", pattern, handler)
 }
 
-func (g *RouterGroup) Static(relativePath string, root string) {
-	handler := g.createStaticHandler(relativePath, http.Dir(root))
+func (g *RouterGroup) POST(pattern string, handler HandlerFunc) *Route {
+	return g.addRoute("POST", pattern, handler)
+}
 
-	urlPattern := path.Join(relativePath, "/*filepath")
+func (g *RouterGroup) PUT(pattern string, handler HandlerFunc) *Route {
+	return g.addRoute("PUT", pattern, handler)
+}
 
-	g.GET(urlPattern, handler)
+func (g *RouterGroup) PATCH(pattern string, handler HandlerFunc) *Route {
+	return g.addRoute("PATCH", pattern, handler)
 }
 
-// Logger
-// 记录一个请求的耗时
-func Logger() HandlerFunc {
-	return func(c *Context) {
-		t := time.Now()
+func (g *RouterGroup) DELETE(pattern string, handler HandlerFunc) *Route {
+	return g.addRoute("DELETE", pattern, handler)
+}
 
-		c.Next()
+func (g *RouterGroup) HEAD(pattern string, handler HandlerFunc) *Route {
+	return g.addRoute("HEAD", pattern, handler)
+}
 
-		log.Printf("[%d] %s in %v\n", c.StatusCode, c.Req.RequestURI, time.Since(t))
-	}
+func (g *RouterGroup) OPTIONS(pattern string, handler HandlerFunc) *Route {
+	return g.addRoute("OPTIONS", pattern, handler)
 }
 
-func (e *Engine) Run(addr string) error {
-	return http.ListenAndServe(addr, e)
+// Any
+// 为所有常用的请求方法注册同一个处理函数,返回的RouteSet可以一次性给所有方法的路由命名或补充文档
+func (g *RouterGroup) Any(pattern string, handler HandlerFunc) RouteSet {
+	routes := make(RouteSet, 0, len(anyMethods))
+	for _, method := range anyMethods {
+		routes = append(routes, g.addRoute(method, pattern, handler))
+	}
+	return routes
 }
 
+// ServeHTTP
+// 处理链在注册路由时已经组装好,这里只需要一次路由查找
 func (e *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	var middlewares []HandlerFunc
-
-	for _, group := range e.routerGroups {
-		if strings.HasPrefix(req.URL.Path, group.prefix) {
-			middlewares = append(middlewares, group.middlewares...)
-		}
-
-	}
+	atomic.AddInt32(&e.inflight, 1)
+	defer e.requestDone()
 
-	c := NewContext(w, req)
-	c.e = e
-	c.handlers = middlewares
+	c := e.pool.Get().(*Context)
+	c.reset(w, req)
 	e.router.handle(c)
+	e.pool.Put(c)
 }
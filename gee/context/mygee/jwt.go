@@ -0,0 +1,229 @@
+package mygee
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+	"time"
+)
+
+/*
+	只依赖标准库的jwt校验,支持HS256/HS384/HS512
+	校验签名以及exp、nbf、iss、aud,允许ClockSkew范围内的时钟误差
+*/
+
+var jwtAlgorithms = map[string]func() hash.Hash{
+	"HS256": sha256.New,
+	"HS384": sha512.New384,
+	"HS512": sha512.New,
+}
+
+// Claims jwt中的声明
+type Claims map[string]interface{}
+
+// Subject 返回sub声明
+func (cl Claims) Subject() string {
+	s, _ := cl["sub"].(string)
+	return s
+}
+
+// audience aud可以是字符串也可以是字符串数组
+func (cl Claims) audience() []string {
+	switch aud := cl["aud"].(type) {
+	case string:
+		return []string{aud}
+	case []interface{}:
+		res := make([]string, 0, len(aud))
+		for _, a := range aud {
+			if s, ok := a.(string); ok {
+				res = append(res, s)
+			}
+		}
+		return res
+	}
+	return nil
+}
+
+// numericDate 读取exp、nbf等时间声明
+func (cl Claims) numericDate(name string) (time.Time, bool, error) {
+	v, ok := cl[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	f, ok := v.(float64)
+	if !ok {
+		return time.Time{}, false, fmt.Errorf("%w: %s is not a number", ErrUnauthorized, name)
+	}
+	sec := int64(f)
+	return time.Unix(sec, int64((f-float64(sec))*1e9)), true, nil
+}
+
+// JWTConfig jwt校验配置
+type JWTConfig struct {
+	// Secret HMAC密钥
+	Secret []byte
+	// Algorithms 允许的签名算法,默认为HS256,HS384,HS512
+	Algorithms []string
+	// Issuer 不为空时要求iss与之相等
+	Issuer string
+	// Audience 不为空时要求aud中包含该值
+	Audience string
+	// ClockSkew 校验exp和nbf时允许的时钟误差
+	ClockSkew time.Duration
+	// Validate 额外的校验,例如检查角色,返回ErrForbidden时响应403
+	Validate func(claims Claims) error
+}
+
+// timeNow 校验exp和nbf时使用的当前时间,测试中替换
+var timeNow = time.Now
+
+// JWT
+// 从Authorization: Bearer <token>中读取并校验jwt,通过后把Claims保存为调用方身份
+func JWT(conf JWTConfig) HandlerFunc {
+	if len(conf.Secret) == 0 {
+		panic("mygee: jwt secret can not be empty")
+	}
+	for _, alg := range conf.Algorithms {
+		if _, ok := jwtAlgorithms[alg]; !ok {
+			panic("mygee: unsupported jwt algorithm " + alg)
+		}
+	}
+
+	return func(c *Context) {
+		auth := c.Req.Header.Get("Authorization")
+		if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+			c.abortAuth(ErrUnauthorized, `Bearer`)
+			return
+		}
+		claims, err := ParseJWT(strings.TrimSpace(auth[7:]), conf)
+		if err == nil && conf.Validate != nil {
+			err = conf.Validate(claims)
+		}
+		if err != nil {
+			c.abortAuth(err, `Bearer error="invalid_token"`)
+			return
+		}
+		c.Set(AuthUserKey, claims)
+		c.Next()
+	}
+}
+
+// ParseJWT 校验token并返回其中的Claims,所有校验失败的错误都包装了ErrUnauthorized
+func ParseJWT(token string, conf JWTConfig) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrUnauthorized)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	newHash, ok := jwtAlgorithms[header.Alg]
+	if !ok || !algorithmAllowed(header.Alg, conf.Algorithms) {
+		return nil, fmt.Errorf("%w: unexpected signing algorithm %q", ErrUnauthorized, header.Alg)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed signature", ErrUnauthorized)
+	}
+	mac := hmac.New(newHash, conf.Secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, fmt.Errorf("%w: signature is invalid", ErrUnauthorized)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err := claims.verify(conf); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (cl Claims) verify(conf JWTConfig) error {
+	now := timeNow()
+
+	exp, ok, err := cl.numericDate("exp")
+	if err != nil {
+		return err
+	}
+	if ok && now.After(exp.Add(conf.ClockSkew)) {
+		return fmt.Errorf("%w: token is expired", ErrUnauthorized)
+	}
+	nbf, ok, err := cl.numericDate("nbf")
+	if err != nil {
+		return err
+	}
+	if ok && now.Add(conf.ClockSkew).Before(nbf) {
+		return fmt.Errorf("%w: token is not valid yet", ErrUnauthorized)
+	}
+
+	if conf.Issuer != "" {
+		if iss, _ := cl["iss"].(string); iss != conf.Issuer {
+			return fmt.Errorf("%w: unexpected issuer %q", ErrUnauthorized, iss)
+		}
+	}
+	if conf.Audience != "" {
+		found := false
+		for _, aud := range cl.audience() {
+			found = found || aud == conf.Audience
+		}
+		if !found {
+			return fmt.Errorf("%w: token is not issued for %q", ErrUnauthorized, conf.Audience)
+		}
+	}
+	return nil
+}
+
+func algorithmAllowed(alg string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, a := range allowed {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return fmt.Errorf("%w: malformed token segment", ErrUnauthorized)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: malformed token segment", ErrUnauthorized)
+	}
+	return nil
+}
+
+// SignJWT 使用HMAC签发token,alg为HS256/HS384/HS512
+func SignJWT(claims Claims, alg string, secret []byte) (string, error) {
+	newHash, ok := jwtAlgorithms[alg]
+	if !ok {
+		return "", errors.New("mygee: unsupported jwt algorithm " + alg)
+	}
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signing := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(newHash, secret)
+	mac.Write([]byte(signing))
+	return signing + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
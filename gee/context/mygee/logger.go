@@ -0,0 +1,166 @@
+package mygee
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+	结构化的访问日志,每个请求输出一行json或logfmt
+	记录的是匹配到的路由规则而不是原始路径,避免/user/1,/user/2...各自成为不同的值
+*/
+
+const (
+	LogFormatJSON   = "json"
+	LogFormatLogfmt = "logfmt"
+)
+
+// LoggerConfig 访问日志的配置
+type LoggerConfig struct {
+	// Format 输出格式,json或logfmt,默认为json
+	Format string
+	// Output 日志输出位置,默认为os.Stdout
+	Output io.Writer
+	// SkipPaths 不记录日志的请求路径,可以是原始路径也可以是路由规则
+	SkipPaths []string
+	// Skip 返回true时不记录该请求
+	Skip func(c *Context) bool
+	// Sampling 按路由规则采样,值为N时每N个请求只记录一个,状态码>=500的请求总是记录
+	Sampling map[string]uint64
+}
+
+// accessLog 一条访问日志,字段顺序即输出顺序
+type accessLog struct {
+	Time      string  `json:"time"`
+	Method    string  `json:"method"`
+	Route     string  `json:"route"`
+	Status    int     `json:"status"`
+	Bytes     int     `json:"bytes"`
+	LatencyMs float64 `json:"latency_ms"`
+	ClientIP  string  `json:"client_ip"`
+	UserAgent string  `json:"user_agent"`
+	RequestID string  `json:"request_id,omitempty"`
+	TraceID   string  `json:"trace_id,omitempty"`
+}
+
+// Logger
+// 使用默认配置记录访问日志: json格式输出到标准输出
+func Logger() HandlerFunc {
+	return LoggerWithConfig(LoggerConfig{})
+}
+
+func LoggerWithConfig(conf LoggerConfig) HandlerFunc {
+	out := conf.Output
+	if out == nil {
+		out = os.Stdout
+	}
+	format := conf.Format
+	if format == "" {
+		format = LogFormatJSON
+	}
+	if format != LogFormatJSON && format != LogFormatLogfmt {
+		panic("mygee: unknown log format " + strconv.Quote(format))
+	}
+
+	skip := make(map[string]bool, len(conf.SkipPaths))
+	for _, p := range conf.SkipPaths {
+		skip[p] = true
+	}
+	counters := make(map[string]*uint64, len(conf.Sampling))
+	for route := range conf.Sampling {
+		counters[route] = new(uint64)
+	}
+
+	var mu sync.Mutex
+	return func(c *Context) {
+		start := time.Now()
+		path := c.Path
+
+		c.Next()
+
+		if skip[path] || skip[c.FullPath] || (conf.Skip != nil && conf.Skip(c)) {
+			return
+		}
+		status := c.W.Status()
+		if n := conf.Sampling[c.FullPath]; n > 1 && status < 500 {
+			if atomic.AddUint64(counters[c.FullPath], 1)%n != 1 {
+				return
+			}
+		}
+
+		size := c.W.Size()
+		if size < 0 {
+			size = 0
+		}
+		entry := accessLog{
+			Time:      start.Format(time.RFC3339Nano),
+			Method:    c.Method,
+			Route:     c.FullPath,
+			Status:    status,
+			Bytes:     size,
+			LatencyMs: float64(time.Since(start).Microseconds()) / 1000,
+			ClientIP:  c.ClientIP(),
+			UserAgent: c.Req.UserAgent(),
+			RequestID: c.RequestID(),
+		}
+		if entry.RequestID == "" {
+			entry.RequestID = c.Req.Header.Get(HeaderRequestID)
+		}
+		if tc, ok := c.Trace(); ok {
+			entry.TraceID = tc.TraceID
+		}
+
+		var line []byte
+		if format == LogFormatJSON {
+			line, _ = json.Marshal(entry)
+			line = append(line, '\n')
+		} else {
+			line = entry.logfmt()
+		}
+
+		mu.Lock()
+		out.Write(line)
+		mu.Unlock()
+	}
+}
+
+func (l *accessLog) logfmt() []byte {
+	var buf bytes.Buffer
+	writeLogfmt(&buf, "time", l.Time)
+	writeLogfmt(&buf, "method", l.Method)
+	writeLogfmt(&buf, "route", l.Route)
+	writeLogfmt(&buf, "status", strconv.Itoa(l.Status))
+	writeLogfmt(&buf, "bytes", strconv.Itoa(l.Bytes))
+	writeLogfmt(&buf, "latency_ms", strconv.FormatFloat(l.LatencyMs, 'f', 3, 64))
+	writeLogfmt(&buf, "client_ip", l.ClientIP)
+	writeLogfmt(&buf, "user_agent", l.UserAgent)
+	if l.RequestID != "" {
+		writeLogfmt(&buf, "request_id", l.RequestID)
+	}
+	if l.TraceID != "" {
+		writeLogfmt(&buf, "trace_id", l.TraceID)
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes()
+}
+
+// writeLogfmt 写出一个key=value,值为空或包含空格、等号、引号时加引号
+func writeLogfmt(buf *bytes.Buffer, key, value string) {
+	if buf.Len() > 0 {
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(key)
+	buf.WriteByte('=')
+	if value == "" || strings.ContainsAny(value, " =\"\t\n") {
+		buf.WriteString(strconv.Quote(value))
+		return
+	}
+	buf.WriteString(value)
+}
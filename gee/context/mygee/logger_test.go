@@ -0,0 +1,58 @@
+package mygee
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoggerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	r := New()
+	r.Use(LoggerWithConfig(LoggerConfig{Output: &buf, SkipPaths: []string{"/health"}}))
+	r.GET("/user/:id", func(c *Context) {
+		c.W.Write([]byte("hello"))
+	})
+	r.GET("/health", func(c *Context) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/user/1", nil)
+	req.Header.Set("User-Agent", "gee-test")
+	req.Header.Set("X-Request-ID", "abc")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	var entry accessLog
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid json log %q: %v", buf.String(), err)
+	}
+	if entry.Route != "/user/:id" || entry.Status != 200 || entry.Bytes != 5 ||
+		entry.ClientIP != "192.0.2.1" || entry.UserAgent != "gee-test" || entry.RequestID != "abc" {
+		t.Fatalf("unexpected log entry: %+v", entry)
+	}
+}
+
+func TestLoggerLogfmtSampling(t *testing.T) {
+	var buf bytes.Buffer
+	r := New()
+	r.Use(LoggerWithConfig(LoggerConfig{
+		Format:   LogFormatLogfmt,
+		Output:   &buf,
+		Sampling: map[string]uint64{"/hot": 10},
+	}))
+	r.GET("/hot", func(c *Context) {})
+
+	for i := 0; i < 25; i++ {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/hot", nil))
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 sampled lines, got %d", len(lines))
+	}
+	if !strings.Contains(lines[0], " method=GET route=/hot status=200 bytes=0 ") ||
+		!strings.Contains(lines[0], ` user_agent=""`) {
+		t.Fatalf("unexpected logfmt line: %s", lines[0])
+	}
+}
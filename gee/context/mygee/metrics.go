@@ -0,0 +1,249 @@
+package mygee
+
+import (
+	"bufio"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+	Prometheus文本格式的监控指标
+	Metrics中间件记录请求数、耗时和响应大小的直方图以及正在处理的请求数,
+	标签为请求方法、路由规则和状态码分类(2xx,4xx...),路由规则取自匹配到的路由而不是原始路径,
+	没有匹配到路由的请求统一记为unmatched,避免标签数量无限增长
+	Engine.MetricsHandler以Prometheus文本格式输出同一个Engine上记录的指标
+*/
+
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// unmatchedRoute 没有匹配到路由时route标签的值
+const unmatchedRoute = "unmatched"
+
+var (
+	// durationBuckets 请求耗时直方图的上界,单位为秒
+	durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+	// sizeBuckets 响应大小直方图的上界,单位为字节
+	sizeBuckets = []float64{100, 1000, 10000, 100000, 1e6, 1e7}
+)
+
+// histogram 累计之前的各区间计数
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func (h *histogram) observe(bounds []float64, v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(bounds))
+	}
+	i := sort.SearchFloat64s(bounds, v)
+	if i < len(bounds) {
+		h.counts[i]++
+	}
+	h.sum += v
+	h.count++
+}
+
+type requestSeries struct {
+	method, route, status string
+	duration              histogram
+	size                  histogram
+}
+
+type inflightSeries struct {
+	method, route string
+	value         int64
+}
+
+type metricsRegistry struct {
+	mu       sync.Mutex
+	requests map[string]*requestSeries
+	inflight map[string]*inflightSeries
+}
+
+func newMetricsRegistry() *metricsRegistry {
+	return &metricsRegistry{
+		requests: make(map[string]*requestSeries),
+		inflight: make(map[string]*inflightSeries),
+	}
+}
+
+func (m *metricsRegistry) addInflight(method, route string, delta int64) {
+	key := method + " " + route
+	m.mu.Lock()
+	s, ok := m.inflight[key]
+	if !ok {
+		s = &inflightSeries{method: method, route: route}
+		m.inflight[key] = s
+	}
+	s.value += delta
+	m.mu.Unlock()
+}
+
+func (m *metricsRegistry) observe(method, route, status string, duration time.Duration, size int) {
+	key := method + " " + route + " " + status
+	m.mu.Lock()
+	s, ok := m.requests[key]
+	if !ok {
+		s = &requestSeries{method: method, route: route, status: status}
+		m.requests[key] = s
+	}
+	s.duration.observe(durationBuckets, duration.Seconds())
+	s.size.observe(sizeBuckets, float64(size))
+	m.mu.Unlock()
+}
+
+// metricsMethod 非标准的请求方法统一记为OTHER
+func metricsMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+func statusClass(code int) string {
+	if code < 100 || code > 599 {
+		return "unknown"
+	}
+	return strconv.Itoa(code/100) + "xx"
+}
+
+// Metrics
+// 记录请求指标,通过Engine.MetricsHandler输出
+// 应该注册在最外层,这样被其他中间件终止的请求也会被统计
+func Metrics() HandlerFunc {
+	return func(c *Context) {
+		m := c.e.metrics
+		method, route := metricsMethod(c.Method), c.FullPath
+		if route == "" {
+			route = unmatchedRoute
+		}
+
+		start := time.Now()
+		m.addInflight(method, route, 1)
+		defer m.addInflight(method, route, -1)
+		c.Next()
+
+		size := c.W.Size()
+		if size < 0 {
+			size = 0
+		}
+		m.observe(method, route, statusClass(c.W.Status()), time.Since(start), size)
+	}
+}
+
+// MetricsHandler
+// 以Prometheus文本格式输出Metrics中间件记录的指标
+//
+//	r.GET("/metrics", r.MetricsHandler())
+func (e *Engine) MetricsHandler() HandlerFunc {
+	return func(c *Context) {
+		c.SetHeader("Content-Type", metricsContentType)
+		c.Status(http.StatusOK)
+		w := bufio.NewWriter(c.W)
+		e.metrics.write(w)
+		w.Flush()
+	}
+}
+
+func (m *metricsRegistry) write(w *bufio.Writer) {
+	m.mu.Lock()
+	requests := make([]requestSeries, 0, len(m.requests))
+	for _, s := range m.requests {
+		cp := *s
+		cp.duration.counts = append([]uint64(nil), s.duration.counts...)
+		cp.size.counts = append([]uint64(nil), s.size.counts...)
+		requests = append(requests, cp)
+	}
+	inflight := make([]inflightSeries, 0, len(m.inflight))
+	for _, s := range m.inflight {
+		inflight = append(inflight, *s)
+	}
+	m.mu.Unlock()
+
+	sort.Slice(requests, func(i, j int) bool {
+		a, b := requests[i], requests[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.status < b.status
+	})
+	sort.Slice(inflight, func(i, j int) bool {
+		if inflight[i].route != inflight[j].route {
+			return inflight[i].route < inflight[j].route
+		}
+		return inflight[i].method < inflight[j].method
+	})
+
+	writeMetricHeader(w, "mygee_http_requests_total", "counter", "Total number of HTTP requests.")
+	for _, s := range requests {
+		writeSample(w, "mygee_http_requests_total", requestLabels(&s), "", float64(s.duration.count))
+	}
+
+	writeMetricHeader(w, "mygee_http_request_duration_seconds", "histogram", "HTTP request latency in seconds.")
+	for _, s := range requests {
+		writeHistogram(w, "mygee_http_request_duration_seconds", requestLabels(&s), durationBuckets, &s.duration)
+	}
+
+	writeMetricHeader(w, "mygee_http_response_size_bytes", "histogram", "HTTP response body size in bytes.")
+	for _, s := range requests {
+		writeHistogram(w, "mygee_http_response_size_bytes", requestLabels(&s), sizeBuckets, &s.size)
+	}
+
+	writeMetricHeader(w, "mygee_http_requests_in_flight", "gauge", "Number of HTTP requests currently being served.")
+	for _, s := range inflight {
+		labels := `method="` + escapeLabel(s.method) + `",route="` + escapeLabel(s.route) + `"`
+		writeSample(w, "mygee_http_requests_in_flight", labels, "", float64(s.value))
+	}
+}
+
+func requestLabels(s *requestSeries) string {
+	return `method="` + escapeLabel(s.method) + `",route="` + escapeLabel(s.route) + `",status="` + s.status + `"`
+}
+
+// escapeLabel 转义标签值中的反斜杠、双引号和换行
+func escapeLabel(v string) string {
+	if !strings.ContainsAny(v, "\\\"\n") {
+		return v
+	}
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+func writeMetricHeader(w *bufio.Writer, name, typ, help string) {
+	w.WriteString("# HELP " + name + " " + help + "\n")
+	w.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+func writeSample(w *bufio.Writer, name, labels, suffix string, v float64) {
+	w.WriteString(name + suffix)
+	if labels != "" {
+		w.WriteString("{" + labels + "}")
+	}
+	w.WriteByte(' ')
+	w.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+	w.WriteByte('\n')
+}
+
+func writeHistogram(w *bufio.Writer, name, labels string, bounds []float64, h *histogram) {
+	var cumulative uint64
+	for i, bound := range bounds {
+		if h.counts != nil {
+			cumulative += h.counts[i]
+		}
+		le := strconv.FormatFloat(bound, 'g', -1, 64)
+		writeSample(w, name, labels+`,le="`+le+`"`, "_bucket", float64(cumulative))
+	}
+	writeSample(w, name, labels+`,le="+Inf"`, "_bucket", float64(h.count))
+	writeSample(w, name, labels, "_sum", h.sum)
+	writeSample(w, name, labels, "_count", float64(h.count))
+}
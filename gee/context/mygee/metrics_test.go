@@ -0,0 +1,62 @@
+package mygee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	r := New()
+	r.Use(Metrics())
+	r.GET("/metrics", r.MetricsHandler())
+	r.GET("/user/:id", func(c *Context) {
+		c.String(http.StatusOK, "hello")
+	})
+	r.POST("/user/:id", func(c *Context) {
+		c.AbortWithStatus(http.StatusBadRequest)
+	})
+
+	for _, path := range []string{"/user/1", "/user/2", "/user/3"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/user/1", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("PURGE", "/user/1", nil))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Header().Get("Content-Type") != metricsContentType {
+		t.Fatalf("unexpected content type %q", w.Header().Get("Content-Type"))
+	}
+	body := w.Body.String()
+
+	expected := []string{
+		"# TYPE mygee_http_requests_total counter",
+		`mygee_http_requests_total{method="GET",route="/user/:id",status="2xx"} 3`,
+		`mygee_http_requests_total{method="POST",route="/user/:id",status="4xx"} 1`,
+		`mygee_http_requests_total{method="GET",route="unmatched",status="4xx"} 1`,
+		`mygee_http_requests_total{method="OTHER",route="unmatched",status="4xx"} 1`,
+		`mygee_http_request_duration_seconds_bucket{method="GET",route="/user/:id",status="2xx",le="+Inf"} 3`,
+		`mygee_http_request_duration_seconds_count{method="GET",route="/user/:id",status="2xx"} 3`,
+		`mygee_http_response_size_bytes_bucket{method="GET",route="/user/:id",status="2xx",le="100"} 3`,
+		`mygee_http_response_size_bytes_sum{method="GET",route="/user/:id",status="2xx"} 15`,
+		`mygee_http_requests_in_flight{method="GET",route="/metrics"} 1`,
+		`mygee_http_requests_in_flight{method="GET",route="/user/:id"} 0`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("metrics should contain %q", line)
+		}
+	}
+	if strings.Contains(body, "/user/1") {
+		t.Errorf("raw paths should not be used as labels:\n%s", body)
+	}
+}
+
+func TestEscapeLabel(t *testing.T) {
+	if got := escapeLabel("a\"b\\c\nd"); got != `a\"b\\c\nd` {
+		t.Fatalf("unexpected escaped label %q", got)
+	}
+}
@@ -0,0 +1,356 @@
+// Package mygeetest 在进程内测试mygee应用的客户端
+// 请求直接交给Engine.ServeHTTP处理,不监听端口,响应中的cookie会自动带到之后的请求中
+//
+//	client := mygeetest.New(t, engine)
+//	client.POST("/login").WithForm(url.Values{"name": {"gee"}}).Expect().Status(200)
+//	client.GET("/u/1").WithHeader("Accept", "application/json").Expect().
+//		Status(200).
+//		JSONPath("data.name", "gee").
+//		JSON(&v)
+package mygeetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gee/context/mygee"
+)
+
+// baseURL 请求使用的虚拟地址,cookie按这个地址保存
+var baseURL = &url.URL{Scheme: "http", Host: "example.com", Path: "/"}
+
+// Client 保存cookie和默认请求头,可以连续发送多个请求
+// 断言失败时报告给New传入的t,子测试中应该用子测试的t创建新的Client
+type Client struct {
+	t      testing.TB
+	engine *mygee.Engine
+	jar    http.CookieJar
+	// Header 每个请求都会带上的请求头
+	Header http.Header
+}
+
+func New(t testing.TB, engine *mygee.Engine) *Client {
+	jar, _ := cookiejar.New(nil)
+	return &Client{t: t, engine: engine, jar: jar, Header: make(http.Header)}
+}
+
+// Cookies 返回当前保存的cookie
+func (cl *Client) Cookies() []*http.Cookie {
+	return cl.jar.Cookies(baseURL)
+}
+
+// ClearCookies 丢弃保存的所有cookie
+func (cl *Client) ClearCookies() {
+	cl.jar, _ = cookiejar.New(nil)
+}
+
+func (cl *Client) GET(path string) *Request     { return cl.Request(http.MethodGet, path) }
+func (cl *Client) POST(path string) *Request    { return cl.Request(http.MethodPost, path) }
+func (cl *Client) PUT(path string) *Request     { return cl.Request(http.MethodPut, path) }
+func (cl *Client) PATCH(path string) *Request   { return cl.Request(http.MethodPatch, path) }
+func (cl *Client) DELETE(path string) *Request  { return cl.Request(http.MethodDelete, path) }
+func (cl *Client) HEAD(path string) *Request    { return cl.Request(http.MethodHead, path) }
+func (cl *Client) OPTIONS(path string) *Request { return cl.Request(http.MethodOptions, path) }
+
+func (cl *Client) Request(method, path string) *Request {
+	return &Request{
+		client: cl,
+		method: method,
+		path:   path,
+		header: cl.Header.Clone(),
+		query:  make(url.Values),
+	}
+}
+
+type fileField struct {
+	field, filename string
+	content         []byte
+}
+
+// Request 正在构造的请求
+type Request struct {
+	client  *Client
+	method  string
+	path    string
+	header  http.Header
+	query   url.Values
+	cookies []*http.Cookie
+
+	body        io.Reader
+	contentType string
+	form        url.Values
+	files       []fileField
+
+	err error // 构造请求时的错误,在Expect中报告
+}
+
+func (r *Request) WithHeader(key, value string) *Request {
+	r.header.Set(key, value)
+	return r
+}
+
+func (r *Request) WithQuery(key, value string) *Request {
+	r.query.Add(key, value)
+	return r
+}
+
+// WithCookie 只对本次请求生效的cookie
+func (r *Request) WithCookie(name, value string) *Request {
+	r.cookies = append(r.cookies, &http.Cookie{Name: name, Value: value})
+	return r
+}
+
+func (r *Request) WithBasicAuth(user, password string) *Request {
+	req := http.Request{Header: make(http.Header)}
+	req.SetBasicAuth(user, password)
+	r.header.Set("Authorization", req.Header.Get("Authorization"))
+	return r
+}
+
+func (r *Request) WithBody(contentType string, body io.Reader) *Request {
+	r.contentType, r.body = contentType, body
+	return r
+}
+
+// WithJSON 把obj编码为json作为请求体
+func (r *Request) WithJSON(obj interface{}) *Request {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		r.err = fmt.Errorf("mygeetest: encode json body: %w", err)
+		return r
+	}
+	return r.WithBody(mygee.MIMEJSON, bytes.NewReader(data))
+}
+
+// WithForm 表单字段,和WithFile一起使用时以multipart/form-data发送
+func (r *Request) WithForm(form url.Values) *Request {
+	if r.form == nil {
+		r.form = make(url.Values)
+	}
+	for k, vs := range form {
+		r.form[k] = append(r.form[k], vs...)
+	}
+	return r
+}
+
+func (r *Request) WithFormField(key, value string) *Request {
+	return r.WithForm(url.Values{key: {value}})
+}
+
+// WithFile 添加一个上传文件,请求体会以multipart/form-data发送
+func (r *Request) WithFile(field, filename string, content []byte) *Request {
+	r.files = append(r.files, fileField{field: field, filename: filename, content: content})
+	return r
+}
+
+// build 组装请求体
+func (r *Request) build() (io.Reader, string, error) {
+	if len(r.files) == 0 {
+		if r.form != nil {
+			return strings.NewReader(r.form.Encode()), "application/x-www-form-urlencoded", nil
+		}
+		return r.body, r.contentType, nil
+	}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for k, vs := range r.form {
+		for _, v := range vs {
+			mw.WriteField(k, v)
+		}
+	}
+	for _, f := range r.files {
+		fw, err := mw.CreateFormFile(f.field, f.filename)
+		if err != nil {
+			return nil, "", fmt.Errorf("mygeetest: create multipart file: %w", err)
+		}
+		fw.Write(f.content)
+	}
+	mw.Close()
+	return &buf, mw.FormDataContentType(), nil
+}
+
+// Expect 发送请求并返回响应
+func (r *Request) Expect() *Response {
+	cl := r.client
+	t := cl.t
+	t.Helper()
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	body, contentType, err := r.build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := r.path
+	if len(r.query) > 0 {
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target += sep + r.query.Encode()
+	}
+	req := httptest.NewRequest(r.method, "http://"+baseURL.Host+"/"+strings.TrimPrefix(target, "/"), body)
+	for k, vs := range r.header {
+		req.Header[k] = vs
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	for _, cookie := range cl.jar.Cookies(req.URL) {
+		req.AddCookie(cookie)
+	}
+	for _, cookie := range r.cookies {
+		req.AddCookie(cookie)
+	}
+
+	w := httptest.NewRecorder()
+	cl.engine.ServeHTTP(w, req)
+	resp := w.Result()
+	resp.Request = req
+	if cookies := resp.Cookies(); len(cookies) > 0 {
+		cl.jar.SetCookies(req.URL, cookies)
+	}
+	return &Response{t: t, Raw: resp, body: w.Body.Bytes()}
+}
+
+// Response 响应以及对它的断言,断言失败时调用t.Errorf,之后的断言继续执行
+type Response struct {
+	t    testing.TB
+	Raw  *http.Response
+	body []byte
+}
+
+func (r *Response) Body() []byte {
+	return r.body
+}
+
+// Status 断言状态码
+func (r *Response) Status(code int) *Response {
+	r.t.Helper()
+	if r.Raw.StatusCode != code {
+		r.t.Errorf("mygeetest: %s %s: expected status %d, got %d\n%s",
+			r.Raw.Request.Method, r.Raw.Request.URL.RequestURI(), code, r.Raw.StatusCode, r.body)
+	}
+	return r
+}
+
+// Header 断言响应头的值
+func (r *Response) Header(key, value string) *Response {
+	r.t.Helper()
+	if got := r.Raw.Header.Get(key); got != value {
+		r.t.Errorf("mygeetest: expected header %s to be %q, got %q", key, value, got)
+	}
+	return r
+}
+
+// HeaderContains 断言响应头包含子串
+func (r *Response) HeaderContains(key, substr string) *Response {
+	r.t.Helper()
+	if got := r.Raw.Header.Get(key); !strings.Contains(got, substr) {
+		r.t.Errorf("mygeetest: expected header %s to contain %q, got %q", key, substr, got)
+	}
+	return r
+}
+
+// BodyEqual 断言响应体
+func (r *Response) BodyEqual(body string) *Response {
+	r.t.Helper()
+	if string(r.body) != body {
+		r.t.Errorf("mygeetest: expected body %q, got %q", body, r.body)
+	}
+	return r
+}
+
+// BodyContains 断言响应体包含子串
+func (r *Response) BodyContains(substr string) *Response {
+	r.t.Helper()
+	if !bytes.Contains(r.body, []byte(substr)) {
+		r.t.Errorf("mygeetest: expected body to contain %q, got %q", substr, r.body)
+	}
+	return r
+}
+
+// Cookie 返回响应中设置的cookie,没有时为nil
+func (r *Response) Cookie(name string) *http.Cookie {
+	for _, cookie := range r.Raw.Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+	return nil
+}
+
+// JSON 把响应体解码到v中,解码失败时终止测试
+func (r *Response) JSON(v interface{}) *Response {
+	r.t.Helper()
+	if err := json.Unmarshal(r.body, v); err != nil {
+		r.t.Fatalf("mygeetest: decode json body %q: %v", r.body, err)
+	}
+	return r
+}
+
+// JSONPath
+// 断言json响应体中path处的值,path以.分隔,数组下标可以写成items.0或items[0]
+// expected会先编码为json再解码,所以数字可以直接写成int
+func (r *Response) JSONPath(path string, expected interface{}) *Response {
+	r.t.Helper()
+	var doc interface{}
+	if err := json.Unmarshal(r.body, &doc); err != nil {
+		r.t.Fatalf("mygeetest: decode json body %q: %v", r.body, err)
+	}
+	got, err := lookupPath(doc, path)
+	if err != nil {
+		r.t.Errorf("mygeetest: json path %q: %v", path, err)
+		return r
+	}
+
+	data, err := json.Marshal(expected)
+	if err != nil {
+		r.t.Fatalf("mygeetest: encode expected value: %v", err)
+	}
+	var want interface{}
+	json.Unmarshal(data, &want)
+	if !reflect.DeepEqual(got, want) {
+		r.t.Errorf("mygeetest: json path %q: expected %s, got %v", path, data, got)
+	}
+	return r
+}
+
+func lookupPath(doc interface{}, path string) (interface{}, error) {
+	path = strings.NewReplacer("[", ".", "]", "").Replace(path)
+	if path == "" || path == "." {
+		return doc, nil
+	}
+	cur := doc
+	for _, key := range strings.Split(strings.TrimPrefix(path, "."), ".") {
+		switch v := cur.(type) {
+		case map[string]interface{}:
+			next, ok := v[key]
+			if !ok {
+				return nil, fmt.Errorf("key %q not found", key)
+			}
+			cur = next
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil, fmt.Errorf("index %s out of range", key)
+			}
+			cur = v[i]
+		default:
+			return nil, fmt.Errorf("can not index %q into %v", key, cur)
+		}
+	}
+	return cur, nil
+}
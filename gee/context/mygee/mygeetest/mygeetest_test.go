@@ -0,0 +1,104 @@
+package mygeetest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"gee/context/mygee"
+)
+
+func testEngine() *mygee.Engine {
+	r := mygee.New()
+	r.Use(mygee.Sessions("sid", mygee.NewCookieStore(mygee.SessionKey{HashKey: []byte("mygeetest")})))
+	r.POST("/login", func(c *mygee.Context) {
+		c.Session().Set("user", c.PostForm("name"))
+		c.String(http.StatusOK, "ok")
+	})
+	r.GET("/u/:id", func(c *mygee.Context) {
+		c.JSON(http.StatusOK, mygee.H{
+			"data": mygee.H{"id": c.Param("id"), "user": c.Session().Get("user"), "tags": []string{"a", c.Query("tag")}},
+		})
+	})
+	r.POST("/upload", func(c *mygee.Context) {
+		var form struct {
+			Title string `form:"title"`
+		}
+		if err := c.ShouldBind(&form); err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		_, fh, err := c.Req.FormFile("file")
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		f, _ := fh.Open()
+		defer f.Close()
+		data, _ := io.ReadAll(f)
+		c.String(http.StatusCreated, "%s:%s:%s", form.Title, fh.Filename, data)
+	})
+	return r
+}
+
+func TestClient(t *testing.T) {
+	client := New(t, testEngine())
+
+	client.POST("/login").WithForm(url.Values{"name": {"gee"}}).Expect().
+		Status(http.StatusOK).
+		HeaderContains("Content-Type", "text/plain")
+	if len(client.Cookies()) != 1 {
+		t.Fatalf("session cookie should be kept, got %v", client.Cookies())
+	}
+
+	var v struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	client.GET("/u/1").WithQuery("tag", "b").Expect().
+		Status(http.StatusOK).
+		Header("Content-Type", "application/json").
+		JSONPath("data.user", "gee").
+		JSONPath("data.tags[1]", "b").
+		JSONPath("data.tags.0", "a").
+		JSON(&v)
+	if v.Data.ID != "1" {
+		t.Fatalf("unexpected json body %+v", v)
+	}
+
+	client.ClearCookies()
+	client.GET("/u/2").Expect().JSONPath("data.user", nil)
+
+	client.POST("/upload").WithFormField("title", "report").WithFile("file", "a.txt", []byte("hello")).Expect().
+		Status(http.StatusCreated).
+		BodyEqual("report:a.txt:hello")
+}
+
+// recorder 记录断言失败信息,用来测试断言本身
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertionsFail(t *testing.T) {
+	rec := &recorder{TB: t}
+	New(rec, testEngine()).GET("/u/1").Expect().
+		Status(http.StatusTeapot).
+		Header("Content-Type", "text/html").
+		BodyContains("missing").
+		JSONPath("data.id", "2").
+		JSONPath("data.nothing", 1).
+		JSONPath("data.id.deeper", 1)
+	if len(rec.errors) != 6 {
+		t.Fatalf("expected 6 failed assertions, got %d: %q", len(rec.errors), rec.errors)
+	}
+}
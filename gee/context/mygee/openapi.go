@@ -0,0 +1,635 @@
+package mygee
+
+import (
+	"encoding"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/*
+	OpenAPI文档
+	路径和路由参数来自前缀树中注册的路由规则,:name和*name都转换为{name}
+	请求和响应的schema通过反射从Route.Request、Route.Response声明的结构体生成:
+	uri/query tag的字段成为路由参数和查询参数,form tag的字段成为表单请求体,其余按json编码规则生成json请求体,
+	validate tag中的required、min、max、len、oneof、email、regex转换为对应的schema约束
+	命名的结构体放在components/schemas中通过$ref引用
+*/
+
+const openAPIVersion = "3.0.3"
+
+var (
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// OpenAPIInfo 文档的info部分
+type OpenAPIInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// routeDoc 路由的文档信息
+type routeDoc struct {
+	summary     string
+	description string
+	tags        []string
+	deprecated  bool
+	request     reflect.Type
+	responses   map[int]reflect.Type // 值为nil表示响应没有内容
+}
+
+func (rt *Route) doc() *routeDoc {
+	if rt.n.doc == nil {
+		rt.n.doc = &routeDoc{}
+	}
+	return rt.n.doc
+}
+
+// Summary 接口的简短说明
+func (rt *Route) Summary(summary string) *Route {
+	rt.doc().summary = summary
+	return rt
+}
+
+// Description 接口的详细说明,支持CommonMark
+func (rt *Route) Description(description string) *Route {
+	rt.doc().description = description
+	return rt
+}
+
+// Tags 接口的分类
+func (rt *Route) Tags(tags ...string) *Route {
+	d := rt.doc()
+	d.tags = append(d.tags, tags...)
+	return rt
+}
+
+// Deprecated 标记接口已经废弃
+func (rt *Route) Deprecated() *Route {
+	rt.doc().deprecated = true
+	return rt
+}
+
+// Request
+// 声明处理函数中Bind使用的结构体,传入结构体的值或者指针均可
+//
+//	r.POST("/users/:id", updateUser).Request(UpdateUser{}).Response(http.StatusOK, User{})
+func (rt *Route) Request(obj interface{}) *Route {
+	t := reflect.TypeOf(obj)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		panic("mygee: route request must be a struct")
+	}
+	rt.doc().request = t
+	return rt
+}
+
+// Response 声明状态码为code时返回的json结构,obj为nil表示没有响应内容
+func (rt *Route) Response(code int, obj interface{}) *Route {
+	d := rt.doc()
+	if d.responses == nil {
+		d.responses = make(map[int]reflect.Type)
+	}
+	d.responses[code] = reflect.TypeOf(obj)
+	return rt
+}
+
+func (rs RouteSet) Summary(summary string) RouteSet {
+	for _, rt := range rs {
+		rt.Summary(summary)
+	}
+	return rs
+}
+
+func (rs RouteSet) Description(description string) RouteSet {
+	for _, rt := range rs {
+		rt.Description(description)
+	}
+	return rs
+}
+
+func (rs RouteSet) Tags(tags ...string) RouteSet {
+	for _, rt := range rs {
+		rt.Tags(tags...)
+	}
+	return rs
+}
+
+func (rs RouteSet) Deprecated() RouteSet {
+	for _, rt := range rs {
+		rt.Deprecated()
+	}
+	return rs
+}
+
+func (rs RouteSet) Request(obj interface{}) RouteSet {
+	for _, rt := range rs {
+		rt.Request(obj)
+	}
+	return rs
+}
+
+func (rs RouteSet) Response(code int, obj interface{}) RouteSet {
+	for _, rt := range rs {
+		rt.Response(code, obj)
+	}
+	return rs
+}
+
+type openAPIDoc struct {
+	OpenAPI    string                              `json:"openapi"`
+	Info       OpenAPIInfo                         `json:"info"`
+	Paths      map[string]map[string]*apiOperation `json:"paths"`
+	Components *apiComponents                      `json:"components,omitempty"`
+}
+
+type apiComponents struct {
+	Schemas map[string]*apiSchema `json:"schemas"`
+}
+
+type apiOperation struct {
+	Tags        []string                `json:"tags,omitempty"`
+	Summary     string                  `json:"summary,omitempty"`
+	Description string                  `json:"description,omitempty"`
+	OperationID string                  `json:"operationId,omitempty"`
+	Parameters  []*apiParameter         `json:"parameters,omitempty"`
+	RequestBody *apiRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*apiResponse `json:"responses"`
+	Deprecated  bool                    `json:"deprecated,omitempty"`
+}
+
+type apiParameter struct {
+	Name     string     `json:"name"`
+	In       string     `json:"in"`
+	Required bool       `json:"required,omitempty"`
+	Schema   *apiSchema `json:"schema"`
+}
+
+type apiRequestBody struct {
+	Required bool                    `json:"required,omitempty"`
+	Content  map[string]apiMediaType `json:"content"`
+}
+
+type apiResponse struct {
+	Description string                  `json:"description"`
+	Content     map[string]apiMediaType `json:"content,omitempty"`
+}
+
+type apiMediaType struct {
+	Schema *apiSchema `json:"schema"`
+}
+
+type apiSchema struct {
+	Ref                  string                `json:"$ref,omitempty"`
+	Type                 string                `json:"type,omitempty"`
+	Format               string                `json:"format,omitempty"`
+	Items                *apiSchema            `json:"items,omitempty"`
+	Properties           map[string]*apiSchema `json:"properties,omitempty"`
+	AdditionalProperties *apiSchema            `json:"additionalProperties,omitempty"`
+	Required             []string              `json:"required,omitempty"`
+	Enum                 []interface{}         `json:"enum,omitempty"`
+	Pattern              string                `json:"pattern,omitempty"`
+	Minimum              *float64              `json:"minimum,omitempty"`
+	Maximum              *float64              `json:"maximum,omitempty"`
+	MinLength            *int                  `json:"minLength,omitempty"`
+	MaxLength            *int                  `json:"maxLength,omitempty"`
+	MinItems             *int                  `json:"minItems,omitempty"`
+	MaxItems             *int                  `json:"maxItems,omitempty"`
+}
+
+// OpenAPI
+// 根据已注册的路由生成OpenAPI 3文档,info为空时使用默认的标题和版本
+// 没有声明Response的路由默认只有一个200响应
+func (e *Engine) OpenAPI() ([]byte, error) {
+	info := e.OpenAPIInfo
+	if info.Title == "" {
+		info.Title = "mygee"
+	}
+	if info.Version == "" {
+		info.Version = "0.0.0"
+	}
+
+	g := &schemaGenerator{
+		schemas: make(map[string]*apiSchema),
+		names:   make(map[reflect.Type]string),
+	}
+	doc := &openAPIDoc{
+		OpenAPI: openAPIVersion,
+		Info:    info,
+		Paths:   make(map[string]map[string]*apiOperation),
+	}
+	for _, method := range e.router.methods {
+		e.router.root[method].walk(func(n *node) {
+			p := openAPIPath(n.pattern)
+			if doc.Paths[p] == nil {
+				doc.Paths[p] = make(map[string]*apiOperation)
+			}
+			doc.Paths[p][strings.ToLower(method)] = g.operation(method, n)
+		})
+	}
+	if len(g.schemas) > 0 {
+		doc.Components = &apiComponents{Schemas: g.schemas}
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// WriteOpenAPI
+// 把OpenAPI文档写到w,用于命令行导出文档而不启动服务
+//
+//	if len(os.Args) > 1 && os.Args[1] == "openapi" {
+//		if err := r.WriteOpenAPI(os.Stdout); err != nil {
+//			log.Fatal(err)
+//		}
+//		return
+//	}
+func (e *Engine) WriteOpenAPI(w io.Writer) error {
+	spec, err := e.OpenAPI()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(spec, '\n'))
+	return err
+}
+
+// OpenAPIHandler
+// 输出OpenAPI文档,每次请求都重新生成,所以后注册的路由也会出现在文档中
+//
+//	r.GET("/openapi.json", r.OpenAPIHandler())
+func (e *Engine) OpenAPIHandler() HandlerFunc {
+	return func(c *Context) {
+		spec, err := e.OpenAPI()
+		if err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		c.SetHeader("Content-Type", MIMEJSON)
+		c.Data(http.StatusOK, spec)
+	}
+}
+
+// openAPIPath 把路由规则中的:name和*name转换为{name}
+func openAPIPath(pattern string) string {
+	parts := strings.Split(pattern, "/")
+	for i, part := range parts {
+		if part != "" && (part[0] == ':' || part[0] == '*') {
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func (g *schemaGenerator) operation(method string, n *node) *apiOperation {
+	op := &apiOperation{Responses: make(map[string]*apiResponse)}
+	d := n.doc
+	if d == nil {
+		d = &routeDoc{}
+	}
+	op.Summary, op.Description, op.Tags, op.Deprecated = d.summary, d.description, d.tags, d.deprecated
+	if n.name != "" {
+		// 同一个名字可以用在多个请求方法上,operationId需要唯一,所以加上请求方法,例如getUser
+		first, size := utf8.DecodeRuneInString(n.name)
+		op.OperationID = strings.ToLower(method) + string(unicode.ToUpper(first)) + n.name[size:]
+	}
+
+	// 路由中的参数都是必需的,类型默认为string,请求结构体中有对应uri字段时使用字段的类型
+	var uri, query map[string]*apiParameter
+	if d.request != nil {
+		uri = g.parameters(d.request, "uri")
+		query = g.parameters(d.request, "query")
+	}
+	for _, name := range n.paramNames {
+		p := uri[name]
+		if p == nil {
+			p = &apiParameter{Schema: &apiSchema{Type: "string"}}
+		}
+		p.Name, p.In, p.Required = name, "path", true
+		op.Parameters = append(op.Parameters, p)
+	}
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		op.Parameters = append(op.Parameters, query[name])
+	}
+	if d.request != nil {
+		op.RequestBody = g.requestBody(d.request)
+	}
+
+	if len(d.responses) == 0 {
+		op.Responses["200"] = &apiResponse{Description: http.StatusText(http.StatusOK)}
+	}
+	for code, t := range d.responses {
+		resp := &apiResponse{Description: http.StatusText(code)}
+		if resp.Description == "" {
+			resp.Description = strconv.Itoa(code)
+		}
+		if t != nil {
+			resp.Content = map[string]apiMediaType{MIMEJSON: {Schema: g.schemaOf(t)}}
+		}
+		op.Responses[strconv.Itoa(code)] = resp
+	}
+	return op
+}
+
+// parameters 收集结构体中带tag的字段,与绑定时一样,未打tag的结构体字段继续向下查找
+func (g *schemaGenerator) parameters(t reflect.Type, tag string) map[string]*apiParameter {
+	params := make(map[string]*apiParameter)
+	g.eachTagged(t, tag, func(name string, sf reflect.StructField) {
+		s := g.schemaOf(sf.Type)
+		if s == nil {
+			return
+		}
+		params[name] = &apiParameter{Name: name, In: tag, Required: applyRules(s, sf), Schema: s}
+	})
+	return params
+}
+
+func (g *schemaGenerator) eachTagged(t reflect.Type, tag string, fn func(name string, sf reflect.StructField)) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		name := tagName(sf, tag)
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			if sf.Type.Kind() == reflect.Struct && sf.Type != timeType {
+				g.eachTagged(sf.Type, tag, fn)
+			}
+			continue
+		}
+		fn(name, sf)
+	}
+}
+
+// requestBody
+// 有form字段时生成表单请求体,包含文件时为multipart/form-data
+// 结构体没有uri、query、form字段时整个结构体就是json请求体,否则只有带json tag的字段属于json请求体
+func (g *schemaGenerator) requestBody(t reflect.Type) *apiRequestBody {
+	content := make(map[string]apiMediaType)
+
+	form := &apiSchema{Type: "object", Properties: make(map[string]*apiSchema)}
+	formType := "application/x-www-form-urlencoded"
+	g.eachTagged(t, "form", func(name string, sf reflect.StructField) {
+		var s *apiSchema
+		switch {
+		case sf.Type == fileHeaderType:
+			s, formType = &apiSchema{Type: "string", Format: "binary"}, "multipart/form-data"
+		case sf.Type.Kind() == reflect.Slice && sf.Type.Elem() == fileHeaderType:
+			s = &apiSchema{Type: "array", Items: &apiSchema{Type: "string", Format: "binary"}}
+			formType = "multipart/form-data"
+		default:
+			s = g.schemaOf(sf.Type)
+		}
+		if s == nil {
+			return
+		}
+		if applyRules(s, sf) {
+			form.Required = append(form.Required, name)
+		}
+		form.Properties[name] = s
+	})
+	if len(form.Properties) > 0 {
+		content[formType] = apiMediaType{Schema: form}
+	}
+
+	bound := false
+	for _, tag := range []string{"uri", "query", "form"} {
+		g.eachTagged(t, tag, func(string, reflect.StructField) { bound = true })
+	}
+	if !bound {
+		content[MIMEJSON] = apiMediaType{Schema: g.schemaOf(t)}
+	} else {
+		body := g.objectSchema(t, func(sf reflect.StructField) bool {
+			return sf.Tag.Get("json") != ""
+		})
+		if len(body.Properties) > 0 {
+			content[MIMEJSON] = apiMediaType{Schema: body}
+		}
+	}
+
+	if len(content) == 0 {
+		return nil
+	}
+	return &apiRequestBody{Required: true, Content: content}
+}
+
+// schemaGenerator 生成schema,命名的结构体只生成一次并放到components中
+type schemaGenerator struct {
+	schemas map[string]*apiSchema
+	names   map[reflect.Type]string
+}
+
+// schemaOf 按encoding/json的编码规则生成t的schema,无法编码为json的类型返回nil
+func (g *schemaGenerator) schemaOf(t reflect.Type) *apiSchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return &apiSchema{Type: "string", Format: "date-time"}
+	case t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType):
+		return &apiSchema{}
+	case t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType):
+		return &apiSchema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &apiSchema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16:
+		return &apiSchema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return &apiSchema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &apiSchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &apiSchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &apiSchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return &apiSchema{Type: "string", Format: "byte"}
+		}
+		items := g.schemaOf(t.Elem())
+		if items == nil {
+			return nil
+		}
+		return &apiSchema{Type: "array", Items: items}
+	case reflect.Map:
+		values := g.schemaOf(t.Elem())
+		if values == nil {
+			return nil
+		}
+		return &apiSchema{Type: "object", AdditionalProperties: values}
+	case reflect.Interface:
+		return &apiSchema{}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.objectSchema(t, nil)
+		}
+		return &apiSchema{Ref: "#/components/schemas/" + g.component(t)}
+	}
+	return nil
+}
+
+// component 返回结构体在components中的名字,第一次遇到时生成schema
+// 不同包中的同名结构体使用包路径区分
+func (g *schemaGenerator) component(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, taken := g.schemas[name]; taken {
+		name = strings.NewReplacer("/", ".", "~", ".").Replace(t.PkgPath()) + "." + name
+	}
+	g.names[t] = name
+	// 先占位再生成,结构体引用自身时直接使用$ref
+	g.schemas[name] = &apiSchema{}
+	*g.schemas[name] = *g.objectSchema(t, nil)
+	return name
+}
+
+// objectSchema 生成结构体的schema,include不为nil时只包含返回true的字段
+func (g *schemaGenerator) objectSchema(t reflect.Type, include func(sf reflect.StructField) bool) *apiSchema {
+	s := &apiSchema{Type: "object", Properties: make(map[string]*apiSchema)}
+	g.addFields(s, t, include)
+	return s
+}
+
+func (g *schemaGenerator) addFields(s *apiSchema, t reflect.Type, include func(sf reflect.StructField) bool) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Tag.Get("json") == "-" {
+			continue
+		}
+		name := tagName(sf, "json")
+		if sf.Anonymous && name == "" {
+			// 与encoding/json一致,没有json tag的内嵌结构体的字段提升到外层
+			ft := sf.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.addFields(s, ft, include)
+				continue
+			}
+		}
+		if sf.PkgPath != "" || (include != nil && !include(sf)) {
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if _, ok := s.Properties[name]; ok {
+			continue
+		}
+		fs := g.schemaOf(sf.Type)
+		if fs == nil {
+			continue
+		}
+		if applyRules(fs, sf) {
+			s.Required = append(s.Required, name)
+		}
+		s.Properties[name] = fs
+	}
+}
+
+// applyRules 把字段的validate规则转换为schema约束,返回字段是否必需
+func applyRules(s *apiSchema, sf reflect.StructField) bool {
+	rules := sf.Tag.Get("validate")
+	if rules == "-" {
+		return false
+	}
+	t := sf.Type
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	required := false
+	for rules != "" {
+		var key, param string
+		key, param, rules = nextRule(rules)
+		switch key {
+		case "required":
+			required = true
+		case "min", "max", "len":
+			applySize(s, t, key, param)
+		case "oneof":
+			for _, opt := range strings.Fields(param) {
+				s.Enum = append(s.Enum, enumValue(t, opt))
+			}
+		case "email":
+			s.Format = "email"
+		case "regex":
+			s.Pattern = param
+		}
+	}
+	return required
+}
+
+func applySize(s *apiSchema, t reflect.Type, key, param string) {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	n := int(limit)
+	var lo, hi **int
+	switch t.Kind() {
+	case reflect.String:
+		lo, hi = &s.MinLength, &s.MaxLength
+	case reflect.Slice, reflect.Array:
+		lo, hi = &s.MinItems, &s.MaxItems
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if key != "max" {
+			s.Minimum = &limit
+		}
+		if key != "min" {
+			s.Maximum = &limit
+		}
+		return
+	default:
+		return
+	}
+	if key != "max" {
+		*lo = &n
+	}
+	if key != "min" {
+		*hi = &n
+	}
+}
+
+// enumValue 数字字段的枚举值按数字输出
+func enumValue(t reflect.Type, opt string) interface{} {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v, err := strconv.ParseInt(opt, 10, 64); err == nil {
+			return v
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v, err := strconv.ParseUint(opt, 10, 64); err == nil {
+			return v
+		}
+	case reflect.Float32, reflect.Float64:
+		if v, err := strconv.ParseFloat(opt, 64); err == nil {
+			return v
+		}
+	}
+	return opt
+}
@@ -0,0 +1,190 @@
+package mygee
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type apiUser struct {
+	ID      int64     `json:"id"`
+	Name    string    `json:"name" validate:"required,min=2,max=20"`
+	Email   string    `json:"email,omitempty" validate:"email"`
+	Role    string    `json:"role" validate:"oneof=admin user"`
+	Created time.Time `json:"created"`
+	Friends []apiUser `json:"friends,omitempty"`
+	secret  string
+}
+
+type apiUpdateUser struct {
+	ID     int64  `uri:"id"`
+	Notify bool   `query:"notify"`
+	Name   string `json:"name" validate:"required"`
+	Level  int    `json:"level" validate:"min=1,max=5"`
+}
+
+type apiUpload struct {
+	Title string                `form:"title" validate:"required"`
+	File  *multipart.FileHeader `form:"file"`
+}
+
+// lookup 按路径在解析后的文档中取值
+func lookup(t *testing.T, doc interface{}, keys ...interface{}) interface{} {
+	t.Helper()
+	for _, k := range keys {
+		switch k := k.(type) {
+		case string:
+			m, ok := doc.(map[string]interface{})
+			if !ok {
+				t.Fatalf("%v is not an object at %q", doc, k)
+			}
+			doc = m[k]
+		case int:
+			a, ok := doc.([]interface{})
+			if !ok || k >= len(a) {
+				t.Fatalf("%v has no index %d", doc, k)
+			}
+			doc = a[k]
+		}
+	}
+	return doc
+}
+
+func TestOpenAPI(t *testing.T) {
+	r := New()
+	r.OpenAPIInfo = OpenAPIInfo{Title: "users", Version: "1.2.0"}
+	r.GET("/users/:id", func(c *Context) {}).Name("user").
+		Summary("Get a user").Tags("users").Response(http.StatusOK, apiUser{}).Response(http.StatusNotFound, nil)
+	r.PUT("/users/:id", func(c *Context) {}).Name("user").
+		Request(&apiUpdateUser{}).Response(http.StatusNoContent, nil)
+	r.POST("/uploads", func(c *Context) {}).Request(apiUpload{})
+	r.GET("/files/*filepath", func(c *Context) {})
+	r.Any("/ping", func(c *Context) {}).Name("ping").Summary("Ping")
+	r.GET("/orders", func(c *Context) {}).Name("订单")
+	r.GET("/cafe", func(c *Context) {}).Name("éclair")
+
+	spec, err := r.OpenAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	if lookup(t, doc, "openapi") != "3.0.3" || lookup(t, doc, "info", "title") != "users" {
+		t.Fatalf("unexpected header: %v %v", doc["openapi"], doc["info"])
+	}
+
+	get := lookup(t, doc, "paths", "/users/{id}", "get")
+	if lookup(t, get, "operationId") != "getUser" || lookup(t, get, "summary") != "Get a user" {
+		t.Fatalf("unexpected operation: %v", get)
+	}
+	if p := lookup(t, get, "parameters", 0); !reflect.DeepEqual(p, map[string]interface{}{
+		"name": "id", "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"},
+	}) {
+		t.Fatalf("unexpected path parameter: %v", p)
+	}
+	if ref := lookup(t, get, "responses", "200", "content", MIMEJSON, "schema", "$ref"); ref != "#/components/schemas/apiUser" {
+		t.Fatalf("unexpected response schema: %v", ref)
+	}
+	if resp := lookup(t, get, "responses", "404"); !reflect.DeepEqual(resp, map[string]interface{}{"description": "Not Found"}) {
+		t.Fatalf("unexpected 404 response: %v", resp)
+	}
+
+	user := lookup(t, doc, "components", "schemas", "apiUser")
+	if !reflect.DeepEqual(lookup(t, user, "required"), []interface{}{"name"}) {
+		t.Fatalf("unexpected required fields: %v", user)
+	}
+	props := lookup(t, user, "properties").(map[string]interface{})
+	if len(props) != 6 || props["secret"] != nil {
+		t.Fatalf("unexpected properties: %v", props)
+	}
+	want := map[string]interface{}{
+		"id":      map[string]interface{}{"type": "integer", "format": "int64"},
+		"name":    map[string]interface{}{"type": "string", "minLength": 2.0, "maxLength": 20.0},
+		"email":   map[string]interface{}{"type": "string", "format": "email"},
+		"role":    map[string]interface{}{"type": "string", "enum": []interface{}{"admin", "user"}},
+		"created": map[string]interface{}{"type": "string", "format": "date-time"},
+		"friends": map[string]interface{}{"type": "array", "items": map[string]interface{}{"$ref": "#/components/schemas/apiUser"}},
+	}
+	if !reflect.DeepEqual(props, want) {
+		t.Fatalf("unexpected properties:\n%v\nwant\n%v", props, want)
+	}
+
+	put := lookup(t, doc, "paths", "/users/{id}", "put")
+	if lookup(t, put, "operationId") != "putUser" ||
+		lookup(t, put, "parameters", 0, "schema", "type") != "integer" ||
+		lookup(t, put, "parameters", 1, "name") != "notify" || lookup(t, put, "parameters", 1, "in") != "query" {
+		t.Fatalf("unexpected parameters: %v", lookup(t, put, "parameters"))
+	}
+	body := lookup(t, put, "requestBody", "content", MIMEJSON, "schema")
+	if !reflect.DeepEqual(body, map[string]interface{}{
+		"type":     "object",
+		"required": []interface{}{"name"},
+		"properties": map[string]interface{}{
+			"name":  map[string]interface{}{"type": "string"},
+			"level": map[string]interface{}{"type": "integer", "format": "int64", "minimum": 1.0, "maximum": 5.0},
+		},
+	}) {
+		t.Fatalf("unexpected request body: %v", body)
+	}
+
+	upload := lookup(t, doc, "paths", "/uploads", "post", "requestBody", "content", "multipart/form-data", "schema")
+	if lookup(t, upload, "properties", "file", "format") != "binary" ||
+		!reflect.DeepEqual(lookup(t, upload, "required"), []interface{}{"title"}) {
+		t.Fatalf("unexpected upload body: %v", upload)
+	}
+
+	for _, method := range []string{"get", "post", "delete"} {
+		op := lookup(t, doc, "paths", "/ping", method)
+		if lookup(t, op, "summary") != "Ping" || lookup(t, op, "operationId") != method+"Ping" {
+			t.Fatalf("Any metadata should apply to %s: %v", method, op)
+		}
+	}
+
+	// 非ASCII的路由名按字符而不是字节处理首字母
+	if id := lookup(t, doc, "paths", "/orders", "get", "operationId"); id != "get订单" {
+		t.Fatalf("unexpected operationId: %q", id)
+	}
+	if id := lookup(t, doc, "paths", "/cafe", "get", "operationId"); id != "getÉclair" {
+		t.Fatalf("unexpected operationId: %q", id)
+	}
+
+	files := lookup(t, doc, "paths", "/files/{filepath}", "get")
+	if lookup(t, files, "parameters", 0, "name") != "filepath" || lookup(t, files, "responses", "200") == nil {
+		t.Fatalf("unexpected operation: %v", files)
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	r := New()
+	r.GET("/openapi.json", r.OpenAPIHandler())
+	r.GET("/later", func(c *Context) {})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	var doc map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil || w.Header().Get("Content-Type") != MIMEJSON {
+		t.Fatalf("unexpected response: %q %v", w.Header().Get("Content-Type"), err)
+	}
+	if lookup(t, doc, "info", "title") != "mygee" || lookup(t, doc, "paths", "/later", "get") == nil {
+		t.Fatalf("unexpected document: %v", doc)
+	}
+	if _, ok := doc["components"]; ok {
+		t.Fatalf("components should be omitted without schemas: %v", doc)
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteOpenAPI(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if spec, _ := r.OpenAPI(); buf.String() != string(spec)+"\n" {
+		t.Fatalf("WriteOpenAPI should write the document followed by a newline: %q", buf.String())
+	}
+}
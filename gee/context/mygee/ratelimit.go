@@ -0,0 +1,157 @@
+package mygee
+
+import (
+	"container/list"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/*
+	限流
+	RateLimit: 令牌桶限流,按key(默认客户端ip)分别计数,超过限制返回429
+	ConcurrencyLimit: 限制同时处理的请求数,超过时直接返回503
+	两者都可以挂在分组上,每次调用创建独立的计数,例如/login使用严格的限制而/api使用宽松的限制
+*/
+
+var (
+	errRateLimited = errors.New("rate limit exceeded")
+	errOverloaded  = errors.New("server is overloaded")
+)
+
+// RateLimitConfig 令牌桶限流配置
+type RateLimitConfig struct {
+	// Rate 每秒补充的令牌数
+	Rate float64
+	// Burst 桶的容量,即短时间内允许的最大请求数
+	Burst int
+	// KeyFunc 区分不同调用方的key,默认为客户端ip
+	KeyFunc func(c *Context) string
+	// MaxKeys 最多保存的key数量,超过时淘汰最久未访问的,默认为10000
+	MaxKeys int
+}
+
+// KeyByIP 按客户端ip限流
+func KeyByIP(c *Context) string {
+	return c.ClientIP()
+}
+
+// KeyByHeader 按请求头限流,例如按API key
+func KeyByHeader(name string) func(c *Context) string {
+	return func(c *Context) string {
+		return c.Req.Header.Get(name)
+	}
+}
+
+func RateLimit(conf RateLimitConfig) HandlerFunc {
+	if conf.Rate <= 0 || conf.Burst <= 0 {
+		panic("mygee: rate limit requires positive Rate and Burst")
+	}
+	if conf.KeyFunc == nil {
+		conf.KeyFunc = KeyByIP
+	}
+	if conf.MaxKeys <= 0 {
+		conf.MaxKeys = 10000
+	}
+	store := newBucketStore(conf.MaxKeys)
+	limit := strconv.Itoa(conf.Burst)
+
+	return func(c *Context) {
+		allowed, remaining, retryAfter, reset := store.take(conf.KeyFunc(c), conf.Rate, float64(conf.Burst), time.Now())
+
+		h := c.W.Header()
+		h.Set("X-RateLimit-Limit", limit)
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		h.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(reset)))
+		if !allowed {
+			h.Set("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+			c.AbortWithError(http.StatusTooManyRequests, errRateLimited).SetType(ErrorTypePublic)
+			return
+		}
+		c.Next()
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// bucket 一个key对应的令牌桶
+type bucket struct {
+	key    string
+	tokens float64
+	last   time.Time
+}
+
+// bucketStore 有容量上限的令牌桶存储,按最近访问时间淘汰
+type bucketStore struct {
+	mu       sync.Mutex
+	maxKeys  int
+	ll       *list.List
+	elements map[string]*list.Element
+}
+
+func newBucketStore(maxKeys int) *bucketStore {
+	return &bucketStore{
+		maxKeys:  maxKeys,
+		ll:       list.New(),
+		elements: make(map[string]*list.Element),
+	}
+}
+
+// take 尝试取出一个令牌
+// 返回是否允许、剩余令牌数、被拒绝时距离下一个令牌的时间、距离桶重新装满的时间
+func (s *bucketStore) take(key string, rate, burst float64, now time.Time) (bool, int, time.Duration, time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var b *bucket
+	if ele, ok := s.elements[key]; ok {
+		s.ll.MoveToFront(ele)
+		b = ele.Value.(*bucket)
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+		b.last = now
+	} else {
+		b = &bucket{key: key, tokens: burst, last: now}
+		s.elements[key] = s.ll.PushFront(b)
+		if s.ll.Len() > s.maxKeys {
+			oldest := s.ll.Back()
+			s.ll.Remove(oldest)
+			delete(s.elements, oldest.Value.(*bucket).key)
+		}
+	}
+
+	allowed := b.tokens >= 1
+	var retryAfter time.Duration
+	if allowed {
+		b.tokens--
+	} else {
+		retryAfter = time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	}
+	reset := time.Duration((burst - b.tokens) / rate * float64(time.Second))
+	return allowed, int(b.tokens), retryAfter, reset
+}
+
+// ConcurrencyLimit
+// 同时处理的请求数超过max时直接返回503,避免请求堆积拖垮服务
+func ConcurrencyLimit(max int) HandlerFunc {
+	if max <= 0 {
+		panic("mygee: concurrency limit must be positive")
+	}
+	sem := make(chan struct{}, max)
+
+	return func(c *Context) {
+		select {
+		case sem <- struct{}{}:
+		default:
+			c.W.Header().Set("Retry-After", "1")
+			c.AbortWithError(http.StatusServiceUnavailable, errOverloaded).SetType(ErrorTypePublic)
+			return
+		}
+		defer func() { <-sem }()
+		c.Next()
+	}
+}
@@ -0,0 +1,89 @@
+package mygee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBucketStore(t *testing.T) {
+	store := newBucketStore(2)
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		if ok, _, _, _ := store.take("a", 1, 2, now); !ok {
+			t.Fatalf("request %d should be allowed", i)
+		}
+	}
+	ok, remaining, retryAfter, _ := store.take("a", 1, 2, now)
+	if ok || remaining != 0 || retryAfter != time.Second {
+		t.Fatalf("third request should be limited: %v %d %v", ok, remaining, retryAfter)
+	}
+	if ok, _, _, _ := store.take("a", 1, 2, now.Add(time.Second)); !ok {
+		t.Fatalf("token should be refilled after one second")
+	}
+
+	// 超过容量时淘汰最久未访问的key
+	store.take("b", 1, 2, now)
+	store.take("c", 1, 2, now)
+	if _, ok := store.elements["a"]; ok || store.ll.Len() != 2 {
+		t.Fatalf("oldest key should be evicted")
+	}
+}
+
+func TestRateLimitGroups(t *testing.T) {
+	r := New()
+	login := r.Group("/login")
+	login.Use(RateLimit(RateLimitConfig{Rate: 1, Burst: 1, KeyFunc: KeyByHeader("X-Api-Key")}))
+	login.POST("", func(c *Context) {})
+	r.POST("/api", func(c *Context) {})
+
+	codes := make([]int, 0)
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.Header.Set("X-Api-Key", "k1")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		codes = append(codes, w.Code)
+		if i == 1 && (w.Header().Get("Retry-After") != "1" || w.Header().Get("X-RateLimit-Remaining") != "0") {
+			t.Fatalf("unexpected rate limit headers: %v", w.Header())
+		}
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+		t.Fatalf("unexpected status codes: %v", codes)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("/api should not be limited by /login policy, got %d", w.Code)
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	r := New()
+	release := make(chan struct{})
+	entered := make(chan struct{})
+	r.Use(ConcurrencyLimit(1))
+	r.GET("/slow", func(c *Context) {
+		close(entered)
+		<-release
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/slow", nil))
+	}()
+	<-entered
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow", nil))
+	close(release)
+	wg.Wait()
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("excess request should be shed with 503, got %d", w.Code)
+	}
+}
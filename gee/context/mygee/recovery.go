@@ -26,13 +26,31 @@ func trace(message string) string {
 	return res.String()
 }
 
+// traceIDs 返回日志中标识请求的前缀,没有使用RequestID中间件时为空
+func traceIDs(c *Context) string {
+	var res strings.Builder
+	if id := c.RequestID(); id != "" {
+		res.WriteString("request_id=" + id + " ")
+	}
+	if tc, ok := c.Trace(); ok {
+		res.WriteString("trace_id=" + tc.TraceID + " ")
+	}
+	return res.String()
+}
+
 func Recovery() HandlerFunc {
 	return func(c *Context) {
 		defer func() {
 			if err := recover(); err != nil {
 				message := fmt.Sprintf("%s", err)
-				log.Printf("%s\n\n", trace(message))
-				c.String(http.StatusInternalServerError, "Internal Server Error")
+				log.Printf("%s%s\n\n", traceIDs(c), trace(message))
+				// 响应交给ErrorHandler统一写出,已经写出过响应时只记录错误
+				if c.W.Written() {
+					c.Abort()
+					c.Error(fmt.Errorf("panic: %s", message))
+					return
+				}
+				c.AbortWithError(http.StatusInternalServerError, fmt.Errorf("panic: %s", message))
 			}
 		}()
 		c.Next()
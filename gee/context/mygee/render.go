@@ -0,0 +1,248 @@
+package mygee
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+	在context.go基础渲染方式之上补充的渲染方式: xml,带缩进/不转义的json,jsonp,文件,重定向
+	以及根据Accept请求头选择响应格式的内容协商
+*/
+
+const (
+	MIMEJSON  = "application/json"
+	MIMEXML   = "application/xml"
+	MIMEXML2  = "text/xml"
+	MIMEHTML  = "text/html"
+	MIMEPlain = "text/plain"
+)
+
+// jsonpCallback 合法的jsonp回调函数名,防止通过callback参数注入脚本
+var jsonpCallback = regexp.MustCompile(`^[a-zA-Z_$][a-zA-Z0-9_$.]*$`)
+
+func (c *Context) XML(code int, obj interface{}) {
+	c.SetHeader("Content-Type", "application/xml; charset=utf-8")
+	c.Status(code)
+	if err := xml.NewEncoder(c.W).Encode(obj); err != nil {
+		http.Error(c.W, err.Error(), 500)
+	}
+}
+
+// IndentedJSON
+// 带缩进的json,方便调试时阅读
+func (c *Context) IndentedJSON(code int, obj interface{}) {
+	data, err := json.MarshalIndent(obj, "", "    ")
+	if err != nil {
+		http.Error(c.W, err.Error(), 500)
+		return
+	}
+	c.SetHeader("Content-Type", "application/json")
+	c.Status(code)
+	c.W.Write(data)
+}
+
+// PureJSON
+// 不对<,>,&等html字符做转义的json
+func (c *Context) PureJSON(code int, obj interface{}) {
+	c.SetHeader("Content-Type", "application/json")
+	c.Status(code)
+	encode := json.NewEncoder(c.W)
+	encode.SetEscapeHTML(false)
+	if err := encode.Encode(obj); err != nil {
+		http.Error(c.W, err.Error(), 500)
+	}
+}
+
+// JSONP
+// 查询参数callback为空时退化为普通json,callback不合法时返回400
+func (c *Context) JSONP(code int, obj interface{}) {
+	callback := c.Query("callback")
+	if callback == "" {
+		c.JSON(code, obj)
+		return
+	}
+	if !jsonpCallback.MatchString(callback) {
+		c.String(http.StatusBadRequest, "invalid jsonp callback: %q\n", callback)
+		return
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		http.Error(c.W, err.Error(), 500)
+		return
+	}
+	var buf bytes.Buffer
+	buf.WriteString(callback)
+	buf.WriteByte('(')
+	buf.Write(data)
+	buf.WriteString(");")
+
+	c.SetHeader("Content-Type", "application/javascript")
+	c.Status(code)
+	c.W.Write(buf.Bytes())
+}
+
+// File
+// 发送文件内容,由http.ServeContent处理Range、If-Modified-Since等请求头
+func (c *Context) File(filePath string) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil || info.IsDir() {
+		c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
+		return
+	}
+	http.ServeContent(c.W, c.Req, info.Name(), info.ModTime(), f)
+}
+
+// FileAttachment
+// 以附件形式下载文件,浏览器会以filename作为保存的文件名
+func (c *Context) FileAttachment(filePath string, filename string) {
+	if filename == "" {
+		filename = filepath.Base(filePath)
+	}
+	c.SetHeader("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.File(filePath)
+}
+
+// Redirect
+// code只能是3xx,或者是表示资源已创建的201
+func (c *Context) Redirect(code int, location string) {
+	if (code < http.StatusMultipleChoices || code > http.StatusPermanentRedirect) && code != http.StatusCreated {
+		panic(fmt.Sprintf("mygee: cannot redirect with status code %d", code))
+	}
+	http.Redirect(c.W, c.Req, location, code)
+}
+
+// Offers 内容协商时可以提供的格式和对应的数据
+// 特定格式的数据为空时使用Data
+type Offers struct {
+	Offered  []string // 可以提供的MIME类型,Accept中权重相同时按这里的顺序选择
+	Data     interface{}
+	JSONData interface{}
+	XMLData  interface{}
+	HTMLName string
+	HTMLData interface{}
+}
+
+// Negotiate
+// 根据Accept请求头在offers中选择响应格式,没有可以接受的格式时返回406
+func (c *Context) Negotiate(code int, offers Offers) {
+	switch c.NegotiateFormat(offers.Offered...) {
+	case MIMEJSON:
+		c.JSON(code, pick(offers.JSONData, offers.Data))
+	case MIMEXML, MIMEXML2:
+		c.XML(code, pick(offers.XMLData, offers.Data))
+	case MIMEHTML:
+		c.HTML(code, offers.HTMLName, pick(offers.HTMLData, offers.Data))
+	case MIMEPlain:
+		c.String(code, "%v", offers.Data)
+	default:
+		c.String(http.StatusNotAcceptable, "406 NOT ACCEPTABLE: %s\n", c.Req.Header.Get("Accept"))
+	}
+}
+
+func pick(data, fallback interface{}) interface{} {
+	if data != nil {
+		return data
+	}
+	return fallback
+}
+
+type acceptSpec struct {
+	typ string
+	q   float64
+}
+
+// NegotiateFormat
+// 返回offered中Accept最优先接受的MIME类型,没有匹配时返回空字符串
+// 没有Accept请求头时认为接受任意类型,返回offered[0]
+func (c *Context) NegotiateFormat(offered ...string) string {
+	if len(offered) == 0 {
+		return ""
+	}
+	header := c.Req.Header.Get("Accept")
+	if header == "" {
+		return offered[0]
+	}
+
+	specs := parseAccept(header)
+	for _, spec := range specs {
+		if spec.q <= 0 {
+			continue
+		}
+		for _, offer := range offered {
+			if mimeMatch(spec.typ, offer) && !rejected(specs, offer) {
+				return offer
+			}
+		}
+	}
+	return ""
+}
+
+// rejected 匹配offer的最具体的媒体范围q为0时,表示客户端明确不接受该类型,
+// 例如"application/json;q=0, */*"中的*/*不能再选中application/json
+func rejected(specs []acceptSpec, offer string) bool {
+	best := -1
+	for i, spec := range specs {
+		if !mimeMatch(spec.typ, offer) {
+			continue
+		}
+		if best < 0 || strings.Count(spec.typ, "*") < strings.Count(specs[best].typ, "*") {
+			best = i
+		}
+	}
+	return best >= 0 && specs[best].q <= 0
+}
+
+// parseAccept 解析Accept请求头,按权重从高到低、范围从具体到宽泛排序
+func parseAccept(header string) []acceptSpec {
+	specs := make([]acceptSpec, 0)
+	for _, part := range strings.Split(header, ",") {
+		typ, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		specs = append(specs, acceptSpec{typ: typ, q: q})
+	}
+
+	sort.SliceStable(specs, func(i, j int) bool {
+		if specs[i].q != specs[j].q {
+			return specs[i].q > specs[j].q
+		}
+		return strings.Count(specs[i].typ, "*") < strings.Count(specs[j].typ, "*")
+	})
+	return specs
+}
+
+func mimeMatch(accept, offer string) bool {
+	if accept == "*/*" || accept == offer {
+		return true
+	}
+	if strings.HasSuffix(accept, "/*") {
+		return strings.HasPrefix(offer, accept[:len(accept)-1])
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+package mygee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type renderUser struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func TestNegotiate(t *testing.T) {
+	r := New()
+	r.GET("/user", func(c *Context) {
+		c.Negotiate(http.StatusOK, Offers{
+			Offered: []string{MIMEJSON, MIMEXML},
+			Data:    renderUser{Name: "gee"},
+		})
+	})
+
+	testCases := map[string]string{
+		"":                                     "application/json",
+		"application/xml":                      "application/xml; charset=utf-8",
+		"text/html;q=0.9, application/*;q=0.8": "application/json",
+		"application/json;q=0.5, application/xml": "application/xml; charset=utf-8",
+		"application/json;q=0, */*":               "application/xml; charset=utf-8",
+		"*/*;q=0, application/json":               "application/json",
+	}
+	for accept, want := range testCases {
+		req := httptest.NewRequest(http.MethodGet, "/user", nil)
+		req.Header.Set("Accept", accept)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if got := w.Header().Get("Content-Type"); got != want {
+			t.Errorf("Accept %q should yield %s, got %s", accept, want, got)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/user", nil)
+	req.Header.Set("Accept", "text/html")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusNotAcceptable {
+		t.Fatalf("Accept text/html should be 406, got %d", w.Code)
+	}
+}
+
+func TestFileRange(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "a.txt")
+	if err := os.WriteFile(name, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := New()
+	r.GET("/download", func(c *Context) {
+		c.FileAttachment(name, "报告.txt")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/download", nil)
+	req.Header.Set("Range", "bytes=2-4")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "234" {
+		t.Fatalf("range request failed: %d %q", w.Code, w.Body.String())
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != "attachment; filename*=utf-8''%E6%8A%A5%E5%91%8A.txt" {
+		t.Fatalf("unexpected Content-Disposition: %s", cd)
+	}
+}
@@ -0,0 +1,144 @@
+package mygee
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+)
+
+/*
+	对http.ResponseWriter的包装,记录状态码、写出的字节数以及响应头是否已经发送
+	WriteHeader只记录状态码,第一次Write或WriteHeaderNow时才真正发送响应头,
+	所以在写出响应体之前中间件仍然可以修改状态码
+*/
+
+const noWritten = -1
+
+// errDetachedWriter 通过Context.Copy得到的副本写响应时返回
+var errDetachedWriter = errors.New("mygee: can not write response from a copied Context")
+
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+	http.CloseNotifier
+
+	// Status 返回记录的状态码,没有设置时为200
+	Status() int
+	// Size 返回已经写出的响应体字节数,响应头还没发送时为-1
+	Size() int
+	// Written 响应头是否已经发送
+	Written() bool
+	// WriteHeaderNow 立即发送响应头
+	WriteHeaderNow()
+	// Unwrap 返回原始的http.ResponseWriter,供http.ResponseController使用
+	Unwrap() http.ResponseWriter
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+var _ ResponseWriter = &responseWriter{}
+
+func (w *responseWriter) reset(writer http.ResponseWriter) {
+	w.ResponseWriter = writer
+	w.status = http.StatusOK
+	w.size = noWritten
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if code <= 0 || w.status == code {
+		return
+	}
+	if w.Written() {
+		log.Printf("[WARNING] headers were already written, wanted to override status code %d with %d", w.status, code)
+		return
+	}
+	w.status = code
+}
+
+func (w *responseWriter) WriteHeaderNow() {
+	if !w.Written() {
+		w.size = 0
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	w.WriteHeaderNow()
+	n, err := w.ResponseWriter.Write(data)
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) WriteString(s string) (int, error) {
+	w.WriteHeaderNow()
+	n, err := io.WriteString(w.ResponseWriter, s)
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+func (w *responseWriter) Written() bool {
+	return w.size != noWritten
+}
+
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Hijack 连接被接管后视为已经写出响应,框架不会再补写响应头
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("mygee: response writer does not implement http.Hijacker")
+	}
+	if w.size < 0 {
+		w.size = 0
+	}
+	return hijacker.Hijack()
+}
+
+func (w *responseWriter) Flush() {
+	w.WriteHeaderNow()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// CloseNotify 原始ResponseWriter不支持时返回永远不会关闭的channel
+func (w *responseWriter) CloseNotify() <-chan bool {
+	if notifier, ok := w.ResponseWriter.(http.CloseNotifier); ok {
+		return notifier.CloseNotify()
+	}
+	return make(chan bool)
+}
+
+// detachedWriter Context.Copy使用的ResponseWriter,不与任何连接关联
+// 原来的Context会被放回池中复用,副本如果仍然持有原来的writer就会写到其他请求的响应里
+type detachedWriter struct {
+	header http.Header
+}
+
+func (w detachedWriter) Header() http.Header {
+	return w.header
+}
+
+func (w detachedWriter) Write([]byte) (int, error) {
+	return 0, errDetachedWriter
+}
+
+func (w detachedWriter) WriteHeader(int) {}
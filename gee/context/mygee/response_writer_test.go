@@ -0,0 +1,44 @@
+package mygee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseWriter(t *testing.T) {
+	r := New()
+	var status, size int
+	r.Use(func(c *Context) {
+		c.Next()
+		status, size = c.W.Status(), c.W.Size()
+	})
+	r.GET("/direct", func(c *Context) {
+		c.W.WriteHeader(http.StatusCreated)
+		c.W.Write([]byte("hello"))
+		// 响应头已经发送,再次设置状态码会被忽略
+		c.W.WriteHeader(http.StatusInternalServerError)
+	})
+	r.GET("/flush", func(c *Context) {
+		c.W.(http.Flusher).Flush()
+	})
+	r.GET("/hijack", func(c *Context) {
+		if _, _, err := c.W.Hijack(); err == nil {
+			t.Errorf("httptest.ResponseRecorder should not be hijackable")
+		}
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/direct", nil))
+	if w.Code != http.StatusCreated || status != http.StatusCreated || size != 5 {
+		t.Fatalf("direct write tracking failed: code=%d status=%d size=%d", w.Code, status, size)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/flush", nil))
+	if !w.Flushed || status != http.StatusOK || size != 0 {
+		t.Fatalf("flush failed: flushed=%v status=%d size=%d", w.Flushed, status, size)
+	}
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/hijack", nil))
+}
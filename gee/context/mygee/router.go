@@ -3,97 +3,146 @@ package mygee
 import (
 	"log"
 	"net/http"
+	"sort"
 	"strings"
 )
 
 type router struct {
-	root     map[string]*node
-	handlers map[string]HandlerFunc
-}
+	root    map[string]*node
+	methods []string // 已注册的请求方法,按字典序排列
 
-func newRouter() *router {
-	return &router{
-		root:     make(map[string]*node),
-		handlers: make(map[string]HandlerFunc),
-	}
-}
+	// 未匹配到路由时的处理链,同样在注册阶段预先组装好
+	// 405和自动应答OPTIONS的处理链按分组组装,存放在RouterGroup上
+	noRoute []HandlerFunc
 
-func (r *router) parsePatterns(pattern string) []string {
-	parts := strings.Split(pattern, "/")
+	// 通过Engine.NoRoute/NoMethod设置的处理函数,为空时使用默认的404/405
+	noRouteHandlers  []HandlerFunc
+	noMethodHandlers []HandlerFunc
 
-	res := make([]string, 0)
-	for _, part := range parts {
-		if part == "" {
-			continue
-		}
-
-		res = append(res, part)
+	names map[string]string // 路由名到路由规则的映射
+}
 
-		if part[0] == '*' {
-			break
-		}
+func newRouter() *router {
+	return &router{
+		root:  make(map[string]*node),
+		names: make(map[string]string),
 	}
-	return res
 }
 
 // addRoute是启动服务时调用的，生成当前路由的前缀树
-func (r *router) addRoute(method string, pattern string, handler HandlerFunc) {
+// 中间件和处理函数在这里一次性组装成处理链并存到前缀树的节点上
+func (r *router) addRoute(method string, pattern string, group *RouterGroup, handler HandlerFunc) *node {
+	pattern = cleanPath(pattern)
 	log.Printf("Route %4s - %s", method, pattern)
-	parts := r.parsePatterns(pattern)
 
 	_, ok := r.root[method]
 
 	if !ok {
 		r.root[method] = &node{}
+		r.methods = append(r.methods, method)
+		sort.Strings(r.methods)
+	}
+	n := r.root[method].insert(pattern)
+	n.group = group
+	n.handler = handler
+	n.handlers = group.combineHandlers(handler)
+	return n
+}
+
+// rebuild 重新组装所有路由的处理链,在中间件或分组变化后调用
+// groups[0]是Engine对应的根分组
+func (r *router) rebuild(groups []*RouterGroup) {
+	for _, t := range r.root {
+		t.walk(func(n *node) {
+			n.handlers = n.group.combineHandlers(n.handler)
+		})
+	}
+
+	noRoute := r.noRouteHandlers
+	if len(noRoute) == 0 {
+		noRoute = []HandlerFunc{func(c *Context) {
+			c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
+		}}
 	}
-	r.root[method].insert(pattern, parts, 0)
+	r.noRoute = groups[0].combineHandlers(noRoute...)
 
-	key := method + "-" + pattern
-	r.handlers[key] = handler
+	noMethod := r.noMethodHandlers
+	if len(noMethod) == 0 {
+		noMethod = []HandlerFunc{func(c *Context) {
+			c.String(http.StatusMethodNotAllowed, "405 METHOD NOT ALLOWED: %s %s\n", c.Method, c.Path)
+		}}
+	}
+	autoOption := func(c *Context) {
+		c.Status(http.StatusNoContent)
+	}
+	// 路径能匹配到某个分组的路由时,405和OPTIONS同样要经过该分组的中间件,例如分组上的CORS
+	for _, g := range groups {
+		g.noMethod = g.combineHandlers(noMethod...)
+		g.autoOption = g.combineHandlers(autoOption)
+	}
 }
 
 // getRoute 是调用接口时调用的,利用传入的具体路由路径来匹配合适的前缀树
-func (r *router) getRoute(method string, pattern string) (*node, map[string]string) {
-	searchParts := r.parsePatterns(pattern)
-	params := make(map[string]string)
-	n, ok := r.root[method]
+// 匹配到的参数追加到params[:0]中,复用调用方的切片避免每次请求都分配内存
+func (r *router) getRoute(method string, path string, params Params) (*node, Params) {
+	params = params[:0]
+	root, ok := r.root[method]
 	if !ok {
-		return nil, nil
+		return nil, params
 	}
-	n = n.search(searchParts, 0)
+	n, params := root.search(cleanPath(path), params)
 	if n == nil {
-		return nil, nil
+		return nil, params[:0]
 	}
 
-	parts := r.parsePatterns(n.pattern)
+	for i, name := range n.paramNames {
+		params[i].Key = name
+	}
+	return n, params
 
-	for index, part := range parts {
-		if part[0] == ':' && len(part) > 1 {
-			params[part[1:]] = searchParts[index]
-		}
+}
 
-		if part[0] == '*' && len(part) > 1 {
-			params[part[1:]] = strings.Join(searchParts[index:], "/")
+// allowed 返回能匹配该路径的所有请求方法,用于构造Allow响应头,以及第一个匹配到的路由所属的分组
+// 只要路径能匹配上任意方法的前缀树,OPTIONS就由框架自动应答,所以也算在内
+func (r *router) allowed(path string) ([]string, *RouterGroup) {
+	path = cleanPath(path)
+	res := make([]string, 0)
+	var group *RouterGroup
+	hasOptions := false
+	for _, method := range r.methods {
+		if n, _ := r.root[method].search(path, nil); n != nil {
+			res = append(res, method)
+			hasOptions = hasOptions || method == http.MethodOptions
+			if group == nil {
+				group = n.group
+			}
 		}
 	}
-
-	return n, params
-
+	if len(res) > 0 && !hasOptions {
+		res = append(res, http.MethodOptions)
+		sort.Strings(res)
+	}
+	return res, group
 }
 
 func (r *router) handle(c *Context) {
 
-	n, params := r.getRoute(c.Method, c.Path)
+	n, params := r.getRoute(c.Method, c.Path, c.Params)
+	c.Params = params
 	if n != nil {
-		c.Params = params
-		// 注意这里要用前缀树中的pattern,因为addRoute时存到r的handler中的key是前缀树中的pattern
-		// 不能用c.Path
-		key := c.Method + "-" + n.pattern
-		c.handlers = append(c.handlers, r.handlers[key])
+		c.FullPath = n.pattern
+		c.handlers = n.handlers
+	} else if allow, group := r.allowed(c.Path); len(allow) > 0 {
+		// 路径存在但方法不匹配: OPTIONS自动应答,其余方法返回405
+		c.SetHeader("Allow", strings.Join(allow, ", "))
+		if c.Method == http.MethodOptions {
+			c.handlers = group.autoOption
+		} else {
+			c.handlers = group.noMethod
+		}
 	} else {
-		c.handlers = append(c.handlers, func(c *Context) {
-			c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
-		})
+		c.handlers = r.noRoute
 	}
 	c.Next()
+	c.writePending()
 }
@@ -0,0 +1,112 @@
+package mygee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMethodNotAllowed(t *testing.T) {
+	r := New()
+	r.GET("/user/:id", func(c *Context) {})
+	r.PUT("/user/:id", func(c *Context) {})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/user/1", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST /user/1 should be 405, got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, OPTIONS, PUT" {
+		t.Fatalf("unexpected Allow header: %q", allow)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/user/1", nil))
+	if w.Code != http.StatusNoContent || w.Header().Get("Allow") != "GET, OPTIONS, PUT" {
+		t.Fatalf("OPTIONS /user/1 failed: %d %q", w.Code, w.Header().Get("Allow"))
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/none", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("GET /none should be 404, got %d", w.Code)
+	}
+}
+
+func TestGroupMiddlewares(t *testing.T) {
+	r := New()
+	var trace []string
+	mark := func(name string) HandlerFunc {
+		return func(c *Context) {
+			trace = append(trace, name)
+			c.Next()
+		}
+	}
+	v1 := r.Group("/v1")
+	v1.Use(mark("v1"))
+	v1.GET("/hello", func(c *Context) { trace = append(trace, "hello") })
+	r.GET("/v10/hello", func(c *Context) { trace = append(trace, "v10") })
+	// 注册路由之后再添加的中间件也要生效
+	r.Use(mark("engine"))
+
+	testCases := map[string]string{
+		"/v1/hello":  "engine,v1,hello",
+		"/v10/hello": "engine,v10",
+		"/v1/none":   "engine",
+	}
+	for path, want := range testCases {
+		trace = nil
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		if got := strings.Join(trace, ","); got != want {
+			t.Errorf("GET %s ran %q, want %q", path, got, want)
+		}
+	}
+}
+
+// discardWriter 不做任何事的ResponseWriter,避免基准测试统计到httptest.ResponseRecorder的内存分配
+type discardWriter struct{ h http.Header }
+
+func (w *discardWriter) Header() http.Header         { return w.h }
+func (w *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+func allocEngine() *Engine {
+	r := New()
+	patterns, _ := benchRoutes()
+	for _, p := range patterns {
+		r.GET(p, func(c *Context) {})
+	}
+	return r
+}
+
+func TestStaticRouteZeroAlloc(t *testing.T) {
+	r := allocEngine()
+	w := &discardWriter{h: http.Header{}}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/search", nil)
+	if n := testing.AllocsPerRun(100, func() { r.ServeHTTP(w, req) }); n != 0 {
+		t.Fatalf("static route should not allocate, got %v allocs", n)
+	}
+}
+
+func BenchmarkServeStatic(b *testing.B) {
+	r := allocEngine()
+	w := &discardWriter{h: http.Header{}}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/search", nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.ServeHTTP(w, req)
+	}
+}
+
+func BenchmarkServeParam(b *testing.B) {
+	r := allocEngine()
+	w := &discardWriter{h: http.Header{}}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/42/members/7", nil)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.ServeHTTP(w, req)
+	}
+}
@@ -0,0 +1,216 @@
+package mygee
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+/*
+	路由信息
+	Routes列出所有已注册的路由以及处理函数和中间件的名字,RoutesHandler以JSON或HTML输出路由表,用于调试
+	路由可以通过Name命名,URL按名字和参数反向生成路径,避免在代码中手动拼接
+*/
+
+// Route 注册路由后返回的句柄,用于给路由补充信息
+type Route struct {
+	method string
+	n      *node
+	r      *router
+}
+
+// Name
+// 给路由命名,同一个名字只能对应一个路由规则,不同请求方法的同一规则可以使用相同的名字
+//
+//	r.GET("/users/:id", getUser).Name("user")
+//	r.URL("user", "id", "42") // "/users/42"
+func (rt *Route) Name(name string) *Route {
+	if name == "" {
+		panic("mygee: route name can not be empty")
+	}
+	if pattern, ok := rt.r.names[name]; ok && pattern != rt.n.pattern {
+		panic(fmt.Sprintf("mygee: route name %q is already used by %s", name, pattern))
+	}
+	old := rt.n.name
+	rt.r.names[name] = rt.n.pattern
+	rt.n.name = name
+	// 重命名时释放旧名字,同一规则的其他请求方法仍在使用时保留
+	if old != "" && old != name && !rt.r.nameInUse(old) {
+		delete(rt.r.names, old)
+	}
+	return rt
+}
+
+func (r *router) nameInUse(name string) bool {
+	used := false
+	for _, root := range r.root {
+		root.walk(func(n *node) {
+			if n.name == name {
+				used = true
+			}
+		})
+	}
+	return used
+}
+
+// Method 路由的请求方法
+func (rt *Route) Method() string {
+	return rt.method
+}
+
+// Pattern 路由的完整规则,包含分组前缀
+func (rt *Route) Pattern() string {
+	return rt.n.pattern
+}
+
+// RouteSet 同一个处理函数注册的多个路由,例如Any的返回值,方法作用于其中的每一个路由
+type RouteSet []*Route
+
+// Name 给所有路由使用同一个名字,它们的路由规则相同,所以URL的结果也相同
+func (rs RouteSet) Name(name string) RouteSet {
+	for _, rt := range rs {
+		rt.Name(name)
+	}
+	return rs
+}
+
+// RouteInfo 一个路由的信息
+type RouteInfo struct {
+	Method      string   `json:"method"`
+	Path        string   `json:"path"`
+	Name        string   `json:"name,omitempty"`
+	Handler     string   `json:"handler"`
+	Middlewares []string `json:"middlewares"`
+}
+
+// Routes
+// 返回所有已注册的路由,按路径和请求方法排序
+// 中间件按执行顺序排列,包括注册路由之后才Use的中间件
+func (e *Engine) Routes() []RouteInfo {
+	routes := make([]RouteInfo, 0)
+	for method, root := range e.router.root {
+		root.walk(func(n *node) {
+			middlewares := make([]string, 0, len(n.handlers)-1)
+			for _, h := range n.handlers[:len(n.handlers)-1] {
+				middlewares = append(middlewares, nameOfFunction(h))
+			}
+			routes = append(routes, RouteInfo{
+				Method:      method,
+				Path:        n.pattern,
+				Name:        n.name,
+				Handler:     nameOfFunction(n.handler),
+				Middlewares: middlewares,
+			})
+		})
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+	return routes
+}
+
+func nameOfFunction(f interface{}) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(f).Pointer())
+	if fn == nil {
+		return "unknown"
+	}
+	return fn.Name()
+}
+
+// URL
+// 按路由名生成路径,params为参数名和参数值交替排列
+// 参数值会被转义,通配参数中的'/'保留为路径分隔符
+//
+//	r.GET("/files/*filepath", serveFile).Name("file")
+//	r.URL("file", "filepath", "docs/a b.txt") // "/files/docs/a%20b.txt"
+func (e *Engine) URL(name string, params ...string) (string, error) {
+	pattern, ok := e.router.names[name]
+	if !ok {
+		return "", fmt.Errorf("mygee: route %q is not defined", name)
+	}
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("mygee: route %q: params must be key/value pairs", name)
+	}
+	values := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		values[params[i]] = params[i+1]
+	}
+
+	var b strings.Builder
+	used := make(map[string]bool, len(values))
+	for _, part := range strings.Split(pattern, "/")[1:] {
+		b.WriteByte('/')
+		if part == "" || (part[0] != ':' && part[0] != '*') {
+			b.WriteString(part)
+			continue
+		}
+		key := part[1:]
+		value, ok := values[key]
+		if !ok || value == "" {
+			return "", fmt.Errorf("mygee: route %q: missing value for %q", name, key)
+		}
+		used[key] = true
+		if part[0] == ':' {
+			b.WriteString(url.PathEscape(value))
+			continue
+		}
+		segments := strings.Split(strings.TrimPrefix(value, "/"), "/")
+		for i, seg := range segments {
+			segments[i] = url.PathEscape(seg)
+		}
+		b.WriteString(strings.Join(segments, "/"))
+	}
+	for key := range values {
+		if !used[key] {
+			return "", fmt.Errorf("mygee: route %q has no parameter %q", name, key)
+		}
+	}
+	return b.String(), nil
+}
+
+// MustURL 与URL相同,出错时panic,适合在模板函数中使用
+func (e *Engine) MustURL(name string, params ...string) string {
+	u, err := e.URL(name, params...)
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+// RoutesHandler
+// 输出路由表,Accept为text/html时返回HTML表格,否则返回JSON
+// 路由表会暴露内部结构,只应该注册在调试环境或受保护的分组下
+//
+//	debug := r.Group("/debug")
+//	debug.Use(mygee.BasicAuth(accounts))
+//	debug.GET("/routes", r.RoutesHandler())
+func (e *Engine) RoutesHandler() HandlerFunc {
+	return func(c *Context) {
+		routes := e.Routes()
+		if c.NegotiateFormat(MIMEJSON, MIMEHTML) != MIMEHTML {
+			c.JSON(http.StatusOK, routes)
+			return
+		}
+
+		var buf bytes.Buffer
+		buf.WriteString("<!doctype html>\n<title>Routes</title>\n<table>\n")
+		buf.WriteString("<tr><th>Method</th><th>Path</th><th>Name</th><th>Handler</th><th>Middlewares</th></tr>\n")
+		for _, rt := range routes {
+			fmt.Fprintf(&buf, "<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>\n",
+				html.EscapeString(rt.Method), html.EscapeString(rt.Path), html.EscapeString(rt.Name),
+				html.EscapeString(rt.Handler), html.EscapeString(strings.Join(rt.Middlewares, ", ")))
+		}
+		buf.WriteString("</table>\n")
+		c.SetHeader("Content-Type", "text/html; charset=utf-8")
+		c.Data(http.StatusOK, buf.Bytes())
+	}
+}
@@ -0,0 +1,128 @@
+package mygee
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func listUsers(c *Context) {}
+
+func TestRoutes(t *testing.T) {
+	r := New()
+	r.GET("/users", listUsers)
+	api := r.Group("/api")
+	api.Use(Logger())
+	api.POST("/items/:id", func(c *Context) {}).Name("item")
+	r.Use(Recovery())
+
+	routes := r.Routes()
+	if len(routes) != 2 {
+		t.Fatalf("expected 2 routes, got %+v", routes)
+	}
+	item, users := routes[0], routes[1]
+	if item.Method != http.MethodPost || item.Path != "/api/items/:id" || item.Name != "item" {
+		t.Fatalf("unexpected route: %+v", item)
+	}
+	if len(item.Middlewares) != 2 ||
+		!strings.HasSuffix(item.Middlewares[0], "mygee.Recovery.func1") ||
+		!strings.HasSuffix(item.Middlewares[1], "mygee.LoggerWithConfig.func1") {
+		t.Fatalf("unexpected middlewares: %q", item.Middlewares)
+	}
+	if users.Handler != "gee/context/mygee.listUsers" || len(users.Middlewares) != 1 {
+		t.Fatalf("unexpected route: %+v", users)
+	}
+}
+
+func TestURL(t *testing.T) {
+	r := New()
+	r.GET("/users/:id/posts/:slug", func(c *Context) {}).Name("post")
+	r.PUT("/users/:id/posts/:slug", func(c *Context) {}).Name("post")
+	r.GET("/files/*filepath", func(c *Context) {}).Name("file")
+	r.GET("/", func(c *Context) {}).Name("home")
+	r.Any("/any/:id", func(c *Context) {}).Name("any")
+
+	tests := []struct {
+		name   string
+		params []string
+		want   string
+	}{
+		{"post", []string{"id", "42", "slug", "a b/c?d"}, "/users/42/posts/a%20b%2Fc%3Fd"},
+		{"file", []string{"filepath", "docs/a b.txt"}, "/files/docs/a%20b.txt"},
+		{"home", nil, "/"},
+		{"any", []string{"id", "7"}, "/any/7"},
+	}
+	for _, tt := range tests {
+		got, err := r.URL(tt.name, tt.params...)
+		if err != nil || got != tt.want {
+			t.Fatalf("URL(%q, %q) = %q, %v; want %q", tt.name, tt.params, got, err, tt.want)
+		}
+	}
+
+	bad := [][]string{
+		{"missing"},
+		{"post", "id"},
+		{"post", "id", "1"},
+		{"post", "id", "1", "slug", ""},
+		{"post", "id", "1", "slug", "x", "page", "2"},
+	}
+	for _, args := range bad {
+		if u, err := r.URL(args[0], args[1:]...); err == nil {
+			t.Fatalf("URL(%q) should fail, got %q", args, u)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("reusing a route name for another pattern should panic")
+		}
+	}()
+	r.GET("/other", func(c *Context) {}).Name("post")
+}
+
+func TestRenameRoute(t *testing.T) {
+	r := New()
+	r.GET("/a", func(c *Context) {}).Name("a").Name("b")
+	if _, err := r.URL("a"); err == nil {
+		t.Fatal("old name should be released after renaming")
+	}
+	if u, err := r.URL("b"); err != nil || u != "/a" {
+		t.Fatalf("URL(b) = %q, %v", u, err)
+	}
+	r.GET("/other", func(c *Context) {}).Name("a")
+
+	// 同一规则的其他请求方法仍然使用旧名字时保留
+	r.GET("/c", func(c *Context) {}).Name("c")
+	r.PUT("/c", func(c *Context) {}).Name("c").Name("d")
+	if u, err := r.URL("c"); err != nil || u != "/c" {
+		t.Fatalf("URL(c) = %q, %v", u, err)
+	}
+}
+
+func TestRoutesHandler(t *testing.T) {
+	r := New()
+	r.GET("/debug/routes", r.RoutesHandler()).Name("routes")
+	r.GET("/a/<b>", func(c *Context) {})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/debug/routes", nil))
+	var routes []RouteInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &routes); err != nil || len(routes) != 2 {
+		t.Fatalf("unexpected JSON response: %s %v", w.Body.String(), err)
+	}
+	if routes[1].Path != "/debug/routes" || routes[1].Name != "routes" {
+		t.Fatalf("unexpected route: %+v", routes[1])
+	}
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/debug/routes", nil)
+	req.Header.Set("Accept", "text/html,*/*;q=0.8")
+	r.ServeHTTP(w, req)
+	body := w.Body.String()
+	if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/html") ||
+		!strings.Contains(body, "<td>/a/&lt;b&gt;</td>") {
+		t.Fatalf("unexpected HTML response: %s", body)
+	}
+}
@@ -0,0 +1,153 @@
+package mygee
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"sync/atomic"
+)
+
+/*
+	启动服务与优雅关闭
+	Run系列方法都会为Engine创建一个http.Server并记录下来,Shutdown时统一关闭
+	Shutdown会先停止接收新连接,再等待正在处理的请求结束,最后执行OnShutdown注册的回调
+*/
+
+// Run 监听tcp地址
+func (e *Engine) Run(addr string) error {
+	if err := e.html.prepare(); err != nil {
+		return err
+	}
+	srv := e.newServer(addr)
+	if srv == nil {
+		return http.ErrServerClosed
+	}
+	return e.wait(srv.ListenAndServe())
+}
+
+// RunTLS 监听tcp地址并使用https
+func (e *Engine) RunTLS(addr string, certFile string, keyFile string) error {
+	if err := e.html.prepare(); err != nil {
+		return err
+	}
+	srv := e.newServer(addr)
+	if srv == nil {
+		return http.ErrServerClosed
+	}
+	return e.wait(srv.ListenAndServeTLS(certFile, keyFile))
+}
+
+// RunListener 在已有的listener上提供服务
+func (e *Engine) RunListener(l net.Listener) error {
+	if err := e.html.prepare(); err != nil {
+		return err
+	}
+	srv := e.newServer(l.Addr().String())
+	if srv == nil {
+		return http.ErrServerClosed
+	}
+	return e.wait(srv.Serve(l))
+}
+
+// RunUnix 监听unix socket,启动前会删除遗留的socket文件
+func (e *Engine) RunUnix(file string) error {
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	l, err := net.Listen("unix", file)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file)
+	return e.RunListener(l)
+}
+
+// OnShutdown
+// 注册Shutdown时执行的回调,在所有请求处理完成后按注册顺序执行
+// Shutdown超时时请求没有全部结束,回调不会执行
+func (e *Engine) OnShutdown(fn func()) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onShutdown = append(e.onShutdown, fn)
+}
+
+// Shutdown
+// 停止接收新连接并等待正在处理的请求(包括被hijack的长连接)结束
+// ctx到期时返回ctx.Err(),此时仍未结束的请求不再等待,OnShutdown注册的回调也不会执行
+func (e *Engine) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	if e.closing {
+		e.mu.Unlock()
+		return errors.New("mygee: engine is already shutting down")
+	}
+	e.closing = true
+	servers := e.servers
+	hooks := e.onShutdown
+	e.mu.Unlock()
+	defer close(e.closed)
+
+	var err error
+	for _, srv := range servers {
+		if shutdownErr := srv.Shutdown(ctx); shutdownErr != nil && err == nil {
+			err = shutdownErr
+		}
+	}
+
+	// http.Server.Shutdown不会等待被hijack的连接,这里再等待所有处理函数返回
+	atomic.StoreInt32(&e.draining, 1)
+	if atomic.LoadInt32(&e.inflight) == 0 {
+		e.drainOnce.Do(func() { close(e.drained) })
+	}
+	select {
+	case <-e.drained:
+	case <-ctx.Done():
+	}
+	select {
+	case <-e.drained:
+	default:
+		// 仍有处理函数在运行,这时执行回调(例如关闭数据库)会释放它们正在使用的资源
+		return ctx.Err()
+	}
+
+	for _, hook := range hooks {
+		hook()
+	}
+	return err
+}
+
+// requestDone 请求处理完成,Shutdown正在等待且这是最后一个请求时通知Shutdown
+// 不使用sync.WaitGroup,因为Shutdown的Wait可能与新请求的Add同时发生
+func (e *Engine) requestDone() {
+	if atomic.AddInt32(&e.inflight, -1) == 0 && atomic.LoadInt32(&e.draining) == 1 {
+		e.drainOnce.Do(func() { close(e.drained) })
+	}
+}
+
+// newServer 创建并记录http.Server,Engine已经关闭时返回nil
+func (e *Engine) newServer(addr string) *http.Server {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closing {
+		return nil
+	}
+	srv := &http.Server{
+		Addr:         addr,
+		Handler:      e,
+		ReadTimeout:  e.ReadTimeout,
+		WriteTimeout: e.WriteTimeout,
+		IdleTimeout:  e.IdleTimeout,
+	}
+	e.servers = append(e.servers, srv)
+	return srv
+}
+
+// wait 由Shutdown导致的退出要等到Shutdown完成后再返回,保证调用方不会在请求处理完之前退出进程
+func (e *Engine) wait(err error) error {
+	if errors.Is(err, http.ErrServerClosed) {
+		<-e.closed
+		return nil
+	}
+	return err
+}
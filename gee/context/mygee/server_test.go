@@ -0,0 +1,131 @@
+package mygee
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShutdownWaitsForInflight(t *testing.T) {
+	r := New()
+	started := make(chan struct{})
+	r.GET("/slow", func(c *Context) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		c.String(http.StatusOK, "done")
+	})
+	hooked := false
+	r.OnShutdown(func() { hooked = true })
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runErr := make(chan error, 1)
+	go func() { runErr <- r.RunListener(l) }()
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + l.Addr().String() + "/slow")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		body <- string(data)
+	}()
+
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := r.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+	if got := <-body; got != "done" {
+		t.Fatalf("in-flight request was cut off: %q", got)
+	}
+	if err := <-runErr; err != nil {
+		t.Fatalf("RunListener should return nil after shutdown, got %v", err)
+	}
+	if !hooked {
+		t.Fatalf("OnShutdown hook was not called")
+	}
+	if err := r.RunListener(l); err != http.ErrServerClosed {
+		t.Fatalf("running a closed engine should fail, got %v", err)
+	}
+}
+
+// 请求不断进入时Shutdown仍然能等到处理中的请求结束,不会因为WaitGroup的Add与Wait并发而panic
+func TestShutdownWithConcurrentRequests(t *testing.T) {
+	r := New()
+	r.GET("/", func(c *Context) {
+		time.Sleep(time.Millisecond)
+		c.String(http.StatusOK, "ok")
+	})
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+				}
+			}
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- r.Shutdown(ctx) }()
+
+	// 没有http.Server拒绝新请求,Shutdown开始等待后再停止发送
+	time.Sleep(20 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+	if err := <-shutdownErr; err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+}
+
+func TestShutdownTimeoutSkipsHooks(t *testing.T) {
+	r := New()
+	started, release := make(chan struct{}), make(chan struct{})
+	r.GET("/block", func(c *Context) {
+		close(started)
+		<-release
+	})
+	hooked := false
+	r.OnShutdown(func() { hooked = true })
+
+	served := make(chan struct{})
+	go func() {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/block", nil))
+		close(served)
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := r.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("shutdown should time out, got %v", err)
+	}
+	if hooked {
+		t.Fatal("OnShutdown hooks must not run while handlers are still running")
+	}
+	close(release)
+	<-served
+}
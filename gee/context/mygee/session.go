@@ -0,0 +1,440 @@
+package mygee
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+/*
+	会话
+	CookieStore: 会话数据经过HMAC签名(可选AES-GCM加密)后整体保存在cookie中
+	MemoryStore: 会话数据保存在服务端内存中,cookie里只保存签名后的会话id
+	两种存储都支持密钥轮换: 使用第一组密钥签名和加密,解码时依次尝试所有密钥
+	会话数据使用json编码,数字取出后为float64
+*/
+
+// sessionKey 会话在Keys中的key
+const sessionKey = "mygee.session"
+
+const flashKey = "_flash"
+
+var errInvalidSession = errors.New("mygee: invalid session cookie")
+
+// SessionKey 一组会话密钥
+type SessionKey struct {
+	// HashKey HMAC签名密钥,建议32或64字节
+	HashKey []byte
+	// BlockKey AES密钥,长度为16、24或32字节,为空时只签名不加密
+	BlockKey []byte
+}
+
+// SessionStore 会话存储
+type SessionStore interface {
+	// Load 读取请求中名为name的会话,不存在或无效时返回新会话
+	Load(c *Context, name string) (*Session, error)
+	// Save 保存会话并写入cookie
+	Save(c *Context, s *Session) error
+}
+
+// Session 一个会话
+type Session struct {
+	ID      string // 服务端存储时的会话id
+	Values  map[string]interface{}
+	Options CookieOptions
+	IsNew   bool
+
+	name     string
+	store    SessionStore
+	c        *Context
+	modified bool
+	saved    bool
+}
+
+func (s *Session) Name() string {
+	return s.name
+}
+
+func (s *Session) Get(key string) interface{} {
+	return s.Values[key]
+}
+
+func (s *Session) Set(key string, value interface{}) {
+	s.Values[key] = value
+	s.modified = true
+}
+
+func (s *Session) Delete(key string) {
+	delete(s.Values, key)
+	s.modified = true
+}
+
+// Clear 清空会话数据
+func (s *Session) Clear() {
+	for key := range s.Values {
+		delete(s.Values, key)
+	}
+	s.modified = true
+}
+
+// Destroy 清空会话并让浏览器删除cookie
+func (s *Session) Destroy() {
+	s.Clear()
+	s.Options.MaxAge = -1
+}
+
+// AddFlash 添加一条只会被读取一次的消息
+func (s *Session) AddFlash(value interface{}) {
+	flashes, _ := s.Values[flashKey].([]interface{})
+	s.Values[flashKey] = append(flashes, value)
+	s.modified = true
+}
+
+// Flashes 取出并删除所有flash消息
+func (s *Session) Flashes() []interface{} {
+	flashes, _ := s.Values[flashKey].([]interface{})
+	if len(flashes) > 0 {
+		delete(s.Values, flashKey)
+		s.modified = true
+	}
+	return flashes
+}
+
+// Save 立即保存会话并写入Set-Cookie,通常不需要手动调用
+func (s *Session) Save() error {
+	if err := s.store.Save(s.c, s); err != nil {
+		return err
+	}
+	s.modified = false
+	s.saved = true
+	return nil
+}
+
+// Session
+// 返回Sessions中间件加载的会话,没有使用Sessions中间件时panic
+func (c *Context) Session() *Session {
+	return c.MustGet(sessionKey).(*Session)
+}
+
+// Sessions
+// 加载名为name的会话,会话被修改时在发送响应头之前自动保存
+// 响应写出之后再修改会话不会生效
+func Sessions(name string, store SessionStore) HandlerFunc {
+	return func(c *Context) {
+		s, err := store.Load(c, name)
+		if err != nil {
+			log.Printf("mygee: load session %q: %v", name, err)
+		}
+		s.name, s.store, s.c = name, store, c
+		c.Set(sessionKey, s)
+
+		sw := &sessionWriter{ResponseWriter: c.W, s: s}
+		c.W = sw
+		defer func() {
+			c.W = sw.ResponseWriter
+		}()
+		c.Next()
+
+		if !s.modified {
+			return
+		}
+		if c.W.Written() {
+			log.Printf("mygee: session %q was modified after the response was written", name)
+			return
+		}
+		sw.save()
+	}
+}
+
+// sessionWriter 在第一次写出响应之前保存被修改的会话,保证Set-Cookie能够随响应头发送
+type sessionWriter struct {
+	ResponseWriter
+	s *Session
+}
+
+func (w *sessionWriter) save() {
+	if !w.s.modified || w.ResponseWriter.Written() {
+		return
+	}
+	if err := w.s.Save(); err != nil {
+		w.s.c.Error(err)
+	}
+}
+
+func (w *sessionWriter) WriteHeaderNow() {
+	w.save()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *sessionWriter) Write(data []byte) (int, error) {
+	w.save()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *sessionWriter) WriteString(s string) (int, error) {
+	w.save()
+	return w.ResponseWriter.Write([]byte(s))
+}
+
+func (w *sessionWriter) Flush() {
+	w.save()
+	w.ResponseWriter.Flush()
+}
+
+// Hijack 接管连接之前保存会话,WebSocket握手的101响应会带上Set-Cookie
+func (w *sessionWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.save()
+	return w.ResponseWriter.Hijack()
+}
+
+// sessionCodec 负责cookie值的签名、加密和校验
+// 编码格式: base64url(时间戳(8字节) | 数据 | HMAC-SHA256)
+type sessionCodec struct {
+	keys   []SessionKey
+	blocks []cipher.AEAD
+}
+
+func newSessionCodec(keys []SessionKey) *sessionCodec {
+	if len(keys) == 0 {
+		panic("mygee: session store requires at least one key")
+	}
+	cd := &sessionCodec{keys: keys, blocks: make([]cipher.AEAD, len(keys))}
+	for i, key := range keys {
+		if len(key.HashKey) == 0 {
+			panic("mygee: session hash key can not be empty")
+		}
+		if len(key.BlockKey) == 0 {
+			continue
+		}
+		block, err := aes.NewCipher(key.BlockKey)
+		if err != nil {
+			panic(fmt.Sprintf("mygee: invalid session block key: %v", err))
+		}
+		cd.blocks[i], _ = cipher.NewGCM(block)
+	}
+	return cd
+}
+
+func (cd *sessionCodec) mac(key []byte, name string, payload []byte) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(name))
+	m.Write([]byte{'|'})
+	m.Write(payload)
+	return m.Sum(nil)
+}
+
+func (cd *sessionCodec) encode(name string, data []byte) (string, error) {
+	if gcm := cd.blocks[0]; gcm != nil {
+		nonce := make([]byte, gcm.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return "", err
+		}
+		data = gcm.Seal(nonce, nonce, data, []byte(name))
+	}
+	payload := make([]byte, 8, 8+len(data)+sha256.Size)
+	binary.BigEndian.PutUint64(payload, uint64(time.Now().Unix()))
+	payload = append(payload, data...)
+	payload = append(payload, cd.mac(cd.keys[0].HashKey, name, payload)...)
+	return base64.RawURLEncoding.EncodeToString(payload), nil
+}
+
+// decode 校验签名和有效期,maxAge<=0时不检查有效期
+func (cd *sessionCodec) decode(name string, value string, maxAge int) ([]byte, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(raw) < 8+sha256.Size {
+		return nil, errInvalidSession
+	}
+	payload, sig := raw[:len(raw)-sha256.Size], raw[len(raw)-sha256.Size:]
+	if maxAge > 0 {
+		ts := time.Unix(int64(binary.BigEndian.Uint64(payload)), 0)
+		if time.Since(ts) > time.Duration(maxAge)*time.Second {
+			return nil, errInvalidSession
+		}
+	}
+
+	for i, key := range cd.keys {
+		if !hmac.Equal(sig, cd.mac(key.HashKey, name, payload)) {
+			continue
+		}
+		data := payload[8:]
+		gcm := cd.blocks[i]
+		if gcm == nil {
+			return data, nil
+		}
+		if len(data) < gcm.NonceSize() {
+			return nil, errInvalidSession
+		}
+		plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], []byte(name))
+		if err != nil {
+			return nil, errInvalidSession
+		}
+		return plain, nil
+	}
+	return nil, errInvalidSession
+}
+
+var defaultSessionOptions = CookieOptions{
+	Path:     "/",
+	MaxAge:   86400 * 30,
+	HttpOnly: true,
+	SameSite: http.SameSiteLaxMode,
+}
+
+// CookieStore 会话数据保存在cookie中,注意浏览器对单个cookie有4KB的限制
+type CookieStore struct {
+	Options CookieOptions
+	codec   *sessionCodec
+}
+
+// NewCookieStore
+// keys中第一组用于签名和加密,其余用于解码轮换前签发的cookie
+func NewCookieStore(keys ...SessionKey) *CookieStore {
+	return &CookieStore{Options: defaultSessionOptions, codec: newSessionCodec(keys)}
+}
+
+func (st *CookieStore) Load(c *Context, name string) (*Session, error) {
+	s := &Session{Values: make(map[string]interface{}), Options: st.Options, IsNew: true}
+	value, err := c.Cookie(name)
+	if err != nil {
+		return s, nil
+	}
+	data, err := st.codec.decode(name, value, st.Options.MaxAge)
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s.Values); err != nil {
+		return s, err
+	}
+	s.IsNew = false
+	return s, nil
+}
+
+func (st *CookieStore) Save(c *Context, s *Session) error {
+	if s.Options.MaxAge < 0 {
+		c.SetCookie(s.name, "", s.Options)
+		return nil
+	}
+	data, err := json.Marshal(s.Values)
+	if err != nil {
+		return err
+	}
+	value, err := st.codec.encode(s.name, data)
+	if err != nil {
+		return err
+	}
+	c.SetCookie(s.name, value, s.Options)
+	return nil
+}
+
+// MemoryStore 会话数据保存在内存中,适合单实例部署或开发环境
+type MemoryStore struct {
+	Options CookieOptions
+	codec   *sessionCodec
+
+	mu        sync.Mutex
+	sessions  map[string]memorySession
+	lastSweep time.Time
+}
+
+type memorySession struct {
+	values  map[string]interface{}
+	expires time.Time
+}
+
+func NewMemoryStore(keys ...SessionKey) *MemoryStore {
+	return &MemoryStore{
+		Options:   defaultSessionOptions,
+		codec:     newSessionCodec(keys),
+		sessions:  make(map[string]memorySession),
+		lastSweep: time.Now(),
+	}
+}
+
+func (st *MemoryStore) Load(c *Context, name string) (*Session, error) {
+	s := &Session{Values: make(map[string]interface{}), Options: st.Options, IsNew: true}
+	value, err := c.Cookie(name)
+	if err != nil {
+		return s, nil
+	}
+	id, err := st.codec.decode(name, value, st.Options.MaxAge)
+	if err != nil {
+		return s, err
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	ms, ok := st.sessions[string(id)]
+	if !ok || time.Now().After(ms.expires) {
+		return s, nil
+	}
+	for k, v := range ms.values {
+		s.Values[k] = v
+	}
+	s.ID = string(id)
+	s.IsNew = false
+	return s, nil
+}
+
+func (st *MemoryStore) Save(c *Context, s *Session) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.sweep()
+
+	if s.Options.MaxAge < 0 {
+		delete(st.sessions, s.ID)
+		c.SetCookie(s.name, "", s.Options)
+		return nil
+	}
+	if s.ID == "" {
+		buf := make([]byte, 32)
+		if _, err := io.ReadFull(rand.Reader, buf); err != nil {
+			return err
+		}
+		s.ID = base64.RawURLEncoding.EncodeToString(buf)
+	}
+
+	values := make(map[string]interface{}, len(s.Values))
+	for k, v := range s.Values {
+		values[k] = v
+	}
+	maxAge := s.Options.MaxAge
+	if maxAge == 0 {
+		maxAge = defaultSessionOptions.MaxAge
+	}
+	st.sessions[s.ID] = memorySession{values: values, expires: time.Now().Add(time.Duration(maxAge) * time.Second)}
+
+	value, err := st.codec.encode(s.name, []byte(s.ID))
+	if err != nil {
+		return err
+	}
+	c.SetCookie(s.name, value, s.Options)
+	return nil
+}
+
+// sweep 每分钟最多清理一次过期的会话,调用方需要持有锁
+func (st *MemoryStore) sweep() {
+	now := time.Now()
+	if now.Sub(st.lastSweep) < time.Minute {
+		return
+	}
+	st.lastSweep = now
+	for id, ms := range st.sessions {
+		if now.After(ms.expires) {
+			delete(st.sessions, id)
+		}
+	}
+}
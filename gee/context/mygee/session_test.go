@@ -0,0 +1,145 @@
+package mygee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func sessionEngine(store SessionStore) *Engine {
+	r := New()
+	r.Use(Sessions("gee_session", store))
+	r.POST("/login", func(c *Context) {
+		c.Session().Set("user", "geektutu")
+		c.Session().AddFlash("welcome")
+		c.String(http.StatusOK, "ok")
+	})
+	r.GET("/me", func(c *Context) {
+		s := c.Session()
+		c.String(http.StatusOK, "%v %v", s.Get("user"), s.Flashes())
+	})
+	r.POST("/logout", func(c *Context) {
+		c.Session().Destroy()
+	})
+	return r
+}
+
+func doWithCookie(r *Engine, method, path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func sessionCookie(t *testing.T, w *httptest.ResponseRecorder) *http.Cookie {
+	t.Helper()
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == "gee_session" {
+			return cookie
+		}
+	}
+	t.Fatalf("no session cookie in %v", w.Header())
+	return nil
+}
+
+func TestSessionStores(t *testing.T) {
+	key := SessionKey{HashKey: []byte("0123456789abcdef0123456789abcdef"), BlockKey: []byte("0123456789abcdef")}
+	stores := map[string]SessionStore{
+		"cookie":    NewCookieStore(key),
+		"cookieMAC": NewCookieStore(SessionKey{HashKey: key.HashKey}),
+		"memory":    NewMemoryStore(key),
+	}
+	for name, store := range stores {
+		r := sessionEngine(store)
+		cookie := sessionCookie(t, doWithCookie(r, http.MethodPost, "/login", nil))
+		if !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+			t.Errorf("%s: unexpected cookie attributes %+v", name, cookie)
+		}
+		if strings.Contains(cookie.Value, "geektutu") {
+			t.Errorf("%s: session value leaked into cookie", name)
+		}
+
+		w := doWithCookie(r, http.MethodGet, "/me", cookie)
+		if body := w.Body.String(); body != "geektutu [welcome]" {
+			t.Errorf("%s: first read got %q", name, body)
+		}
+		// 读取flash会修改会话,响应中带有新的cookie
+		cookie = sessionCookie(t, w)
+		if body := doWithCookie(r, http.MethodGet, "/me", cookie).Body.String(); body != "geektutu []" {
+			t.Errorf("%s: flash should be consumed, got %q", name, body)
+		}
+
+		tampered := *cookie
+		tampered.Value = "x" + cookie.Value[1:]
+		if body := doWithCookie(r, http.MethodGet, "/me", &tampered).Body.String(); body != "<nil> []" {
+			t.Errorf("%s: tampered cookie accepted: %q", name, body)
+		}
+
+		if c := sessionCookie(t, doWithCookie(r, http.MethodPost, "/logout", cookie)); c.MaxAge >= 0 {
+			t.Errorf("%s: logout should delete the cookie, got %+v", name, c)
+		}
+	}
+}
+
+func TestSessionKeyRotation(t *testing.T) {
+	oldKey := SessionKey{HashKey: []byte("old-hash-key"), BlockKey: []byte("old-block-key-16")}
+	newKey := SessionKey{HashKey: []byte("new-hash-key"), BlockKey: []byte("new-block-key-16")}
+
+	cookie := sessionCookie(t, doWithCookie(sessionEngine(NewCookieStore(oldKey)), http.MethodPost, "/login", nil))
+
+	rotated := sessionEngine(NewCookieStore(newKey, oldKey))
+	if body := doWithCookie(rotated, http.MethodGet, "/me", cookie).Body.String(); body != "geektutu [welcome]" {
+		t.Fatalf("old cookie should still be valid after rotation, got %q", body)
+	}
+	if body := doWithCookie(sessionEngine(NewCookieStore(newKey)), http.MethodGet, "/me", cookie).Body.String(); body != "<nil> []" {
+		t.Fatalf("old cookie should be rejected once the old key is removed, got %q", body)
+	}
+}
+
+func TestCookie(t *testing.T) {
+	r := New()
+	r.GET("/cookie", func(c *Context) {
+		v, err := c.Cookie("lang")
+		if err != nil {
+			v = "none"
+		}
+		c.SetCookie("seen", "a b;c", CookieOptions{MaxAge: 60, Secure: true, HttpOnly: true, SameSite: http.SameSiteStrictMode})
+		c.String(http.StatusOK, v)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/cookie", nil)
+	req.AddCookie(&http.Cookie{Name: "lang", Value: "zh%20CN"})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Body.String() != "zh CN" {
+		t.Fatalf("unexpected cookie value %q", w.Body.String())
+	}
+	set := w.Header().Get("Set-Cookie")
+	for _, attr := range []string{"seen=a+b%3Bc", "Path=/", "Max-Age=60", "HttpOnly", "Secure", "SameSite=Strict"} {
+		if !strings.Contains(set, attr) {
+			t.Errorf("Set-Cookie %q should contain %q", set, attr)
+		}
+	}
+}
+
+func TestSessionSavedBeforeUpgrade(t *testing.T) {
+	r := New()
+	r.Use(Sessions("gee_session", NewCookieStore(SessionKey{HashKey: []byte("0123456789abcdef0123456789abcdef")})))
+	r.WS("/ws", func(c *Context, ws *WSConn) {})
+	r.Use(func(c *Context) {
+		c.Session().Set("user", "geektutu")
+		c.Next()
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	client, resp := dialWS(t, srv, "/ws")
+	defer client.conn.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols || !strings.Contains(resp.Header.Get("Set-Cookie"), "gee_session=") {
+		t.Fatalf("101 response should carry the session cookie: %d %v", resp.StatusCode, resp.Header)
+	}
+}
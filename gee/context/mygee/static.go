@@ -0,0 +1,255 @@
+package mygee
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+/*
+	静态文件服务
+	文件来自fs.FS,既可以是磁盘上的目录(os.DirFS),也可以是编译进二进制的embed.FS
+	每个文件只打开一次,响应带强ETag(内容的sha256),由http.ServeContent处理If-None-Match、Range等条件请求
+	打开Precompressed后,客户端接受gzip且存在同名.gz文件时直接发送压缩好的文件
+	SPA模式下没有扩展名的未知路径返回index.html,由前端路由处理
+*/
+
+const indexPage = "index.html"
+
+// StaticConfig 静态文件服务的配置
+type StaticConfig struct {
+	// CacheControl 文件响应的Cache-Control,为空时不设置
+	CacheControl string
+	// Browse 目录下没有index.html时是否列出目录内容,默认关闭
+	Browse bool
+	// Precompressed 客户端接受gzip时优先发送同名的.gz文件
+	Precompressed bool
+	// SPA 没有扩展名且不存在的路径返回根目录下的index.html
+	SPA bool
+}
+
+// Static 把磁盘上的root目录映射到relativePath下
+func (g *RouterGroup) Static(relativePath string, root string) {
+	g.StaticFS(relativePath, os.DirFS(root))
+}
+
+// StaticFS 把fsys映射到relativePath下,可以直接传入embed.FS(用fs.Sub去掉目录前缀)
+func (g *RouterGroup) StaticFS(relativePath string, fsys fs.FS) {
+	g.StaticFSWithConfig(relativePath, fsys, StaticConfig{})
+}
+
+func (g *RouterGroup) StaticFSWithConfig(relativePath string, fsys fs.FS, conf StaticConfig) {
+	if strings.ContainsAny(relativePath, ":*") {
+		panic("mygee: URL parameters can not be used when serving a static folder")
+	}
+	s := &staticServer{fsys: fsys, conf: conf}
+	handler := func(c *Context) {
+		s.serve(c, c.Param("filepath"))
+	}
+
+	urlPattern := path.Join(relativePath, "/*filepath")
+	g.GET(urlPattern, handler)
+	g.HEAD(urlPattern, handler)
+	if conf.SPA {
+		// 通配符不匹配空路径,前端应用的根路径需要单独注册
+		g.GET(relativePath, handler)
+		g.HEAD(relativePath, handler)
+	}
+}
+
+// StaticFile 把磁盘上的单个文件映射到relativePath
+func (g *RouterGroup) StaticFile(relativePath string, filePath string) {
+	g.StaticFileFS(relativePath, filepath.Base(filePath), os.DirFS(filepath.Dir(filePath)))
+}
+
+// StaticFileFS 把fsys中名为name的文件映射到relativePath
+func (g *RouterGroup) StaticFileFS(relativePath string, name string, fsys fs.FS) {
+	if strings.ContainsAny(relativePath, ":*") {
+		panic("mygee: URL parameters can not be used when serving a static file")
+	}
+	s := &staticServer{fsys: fsys}
+	handler := func(c *Context) {
+		s.serve(c, name)
+	}
+	g.GET(relativePath, handler)
+	g.HEAD(relativePath, handler)
+}
+
+type staticServer struct {
+	fsys fs.FS
+	conf StaticConfig
+	// etags 按文件名、大小和修改时间缓存计算好的ETag
+	etags sync.Map
+}
+
+func (s *staticServer) serve(c *Context, name string) {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if name == "" {
+		name = "."
+	}
+
+	f, info, err := s.open(name)
+	if err != nil {
+		s.notFound(c, name)
+		return
+	}
+	defer f.Close()
+
+	if info.IsDir() {
+		// 与http.FileServer一致,目录必须以/结尾,保证页面中的相对路径正确
+		if !strings.HasSuffix(c.Req.URL.Path, "/") {
+			target := path.Base(c.Req.URL.Path) + "/"
+			if q := c.Req.URL.RawQuery; q != "" {
+				target += "?" + q
+			}
+			c.Redirect(http.StatusMovedPermanently, target)
+			return
+		}
+		index, indexInfo, err := s.open(path.Join(name, indexPage))
+		if err == nil {
+			defer index.Close()
+			s.serveFile(c, path.Join(name, indexPage), index, indexInfo)
+			return
+		}
+		if s.conf.Browse {
+			s.listDir(c, name)
+			return
+		}
+		s.notFound(c, name)
+		return
+	}
+	s.serveFile(c, name, f, info)
+}
+
+func (s *staticServer) open(name string) (fs.File, fs.FileInfo, error) {
+	f, err := s.fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, info, nil
+}
+
+// notFound SPA模式下没有扩展名的路径交给前端路由,其余返回404
+func (s *staticServer) notFound(c *Context, name string) {
+	if s.conf.SPA && path.Ext(name) == "" {
+		if f, info, err := s.open(indexPage); err == nil && !info.IsDir() {
+			defer f.Close()
+			s.serveFile(c, indexPage, f, info)
+			return
+		}
+	}
+	c.String(http.StatusNotFound, "404 NOT FOUND: %s\n", c.Path)
+}
+
+func (s *staticServer) serveFile(c *Context, name string, f fs.File, info fs.FileInfo) {
+	if s.conf.SPA && path.Base(name) == indexPage {
+		// index.html引用的资源通常带版本号,index.html本身不能被长期缓存
+		c.SetHeader("Cache-Control", "no-cache")
+	} else if s.conf.CacheControl != "" {
+		c.SetHeader("Cache-Control", s.conf.CacheControl)
+	}
+	if s.conf.Precompressed {
+		c.W.Header().Add("Vary", "Accept-Encoding")
+		if negotiateEncoding(c.Req.Header.Get("Accept-Encoding")) == encodingGzip {
+			if gz, gzInfo, err := s.open(name + ".gz"); err == nil {
+				defer gz.Close()
+				if !gzInfo.IsDir() {
+					s.serveContent(c, name+".gz", gz, gzInfo, name)
+					return
+				}
+			}
+		}
+	}
+	s.serveContent(c, name, f, info, "")
+}
+
+// serveContent 设置ETag后交给http.ServeContent
+// origName不为空时f是origName的gzip压缩版本
+func (s *staticServer) serveContent(c *Context, name string, f fs.File, info fs.FileInfo, origName string) {
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(f)
+		if err != nil {
+			c.AbortWithError(http.StatusInternalServerError, err)
+			return
+		}
+		rs = bytes.NewReader(data)
+	}
+
+	etag, err := s.etag(name, info, rs)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	h := c.W.Header()
+	h.Set("ETag", etag)
+	if origName != "" {
+		h.Set("Content-Encoding", encodingGzip)
+		contentType := mime.TypeByExtension(path.Ext(origName))
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		h.Set("Content-Type", contentType)
+		name = origName
+	}
+	http.ServeContent(c.W, c.Req, path.Base(name), info.ModTime(), rs)
+}
+
+func (s *staticServer) etag(name string, info fs.FileInfo, rs io.ReadSeeker) (string, error) {
+	key := fmt.Sprintf("%s|%d|%d", name, info.Size(), info.ModTime().UnixNano())
+	if v, ok := s.etags.Load(key); ok {
+		return v.(string), nil
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, rs); err != nil {
+		return "", err
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := `"` + hex.EncodeToString(h.Sum(nil)[:16]) + `"`
+	s.etags.Store(key, etag)
+	return etag, nil
+}
+
+func (s *staticServer) listDir(c *Context, name string) {
+	entries, err := fs.ReadDir(s.fsys, name)
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+
+	var buf bytes.Buffer
+	buf.WriteString("<!doctype html>\n<meta name=\"viewport\" content=\"width=device-width\">\n<pre>\n")
+	for _, entry := range entries {
+		entryName := entry.Name()
+		if entry.IsDir() {
+			entryName += "/"
+		}
+		u := url.URL{Path: entryName}
+		fmt.Fprintf(&buf, "<a href=\"%s\">%s</a>\n", u.String(), html.EscapeString(entryName))
+	}
+	buf.WriteString("</pre>\n")
+
+	c.SetHeader("Content-Type", "text/html; charset=utf-8")
+	c.Data(http.StatusOK, buf.Bytes())
+}
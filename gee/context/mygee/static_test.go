@@ -0,0 +1,157 @@
+package mygee
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func gzipped(s string) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(s))
+	zw.Close()
+	return buf.Bytes()
+}
+
+func staticFS() fstest.MapFS {
+	return fstest.MapFS{
+		"index.html":       {Data: []byte("<h1>app</h1>")},
+		"assets/app.js":    {Data: []byte("console.log('gee')")},
+		"assets/app.js.gz": {Data: gzipped("console.log('gee')")},
+		"docs/a.txt":       {Data: []byte("a")},
+		"docs/<b>.txt":     {Data: []byte("b")},
+		"sub/c.txt":        {Data: []byte("c")},
+	}
+}
+
+func serveStatic(r *Engine, path string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestStaticFSETag(t *testing.T) {
+	r := New()
+	r.StaticFSWithConfig("/static", staticFS(), StaticConfig{CacheControl: "public, max-age=31536000"})
+
+	w := serveStatic(r, "/static/assets/app.js", nil)
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || w.Body.String() != "console.log('gee')" ||
+		!strings.HasPrefix(etag, `"`) || w.Header().Get("Cache-Control") != "public, max-age=31536000" ||
+		!strings.Contains(w.Header().Get("Content-Type"), "javascript") {
+		t.Fatalf("unexpected response %d %v %q", w.Code, w.Header(), w.Body.String())
+	}
+
+	w = serveStatic(r, "/static/assets/app.js", http.Header{"If-None-Match": {etag}})
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Fatalf("matching ETag should get 304, got %d", w.Code)
+	}
+	w = serveStatic(r, "/static/assets/app.js", http.Header{"Range": {"bytes=0-6"}})
+	if w.Code != http.StatusPartialContent || w.Body.String() != "console" {
+		t.Fatalf("range request failed: %d %q", w.Code, w.Body.String())
+	}
+
+	for _, path := range []string{"/static/missing.js", "/static/docs/", "/static/../gee.go"} {
+		if w := serveStatic(r, path, nil); w.Code != http.StatusNotFound {
+			t.Errorf("%s should be 404, got %d", path, w.Code)
+		}
+	}
+	if w := serveStatic(r, "/static/docs", nil); w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/static/docs/" {
+		t.Errorf("directory without slash should redirect, got %d %v", w.Code, w.Header())
+	}
+	if w := serveStatic(r, "/static/sub?x=1", nil); w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != "/static/sub/?x=1" {
+		t.Errorf("redirect should keep the query, got %d %v", w.Code, w.Header())
+	}
+}
+
+func TestStaticPrecompressedAndBrowse(t *testing.T) {
+	r := New()
+	r.StaticFSWithConfig("/static", staticFS(), StaticConfig{Precompressed: true, Browse: true})
+
+	w := serveStatic(r, "/static/assets/app.js", http.Header{"Accept-Encoding": {"gzip, deflate"}})
+	if w.Header().Get("Content-Encoding") != "gzip" || !strings.Contains(w.Header().Get("Content-Type"), "javascript") ||
+		w.Header().Get("Vary") != "Accept-Encoding" {
+		t.Fatalf("precompressed file should be served, got %v", w.Header())
+	}
+	zr, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(zr); string(data) != "console.log('gee')" {
+		t.Fatalf("unexpected decompressed body %q", data)
+	}
+	plain := serveStatic(r, "/static/assets/app.js", nil)
+	if plain.Header().Get("Content-Encoding") != "" || plain.Header().Get("ETag") == w.Header().Get("ETag") {
+		t.Fatalf("plain and gzip variants need different ETags: %v", plain.Header())
+	}
+
+	w = serveStatic(r, "/static/docs/", nil)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `<a href="a.txt">a.txt</a>`) ||
+		!strings.Contains(w.Body.String(), "&lt;b&gt;.txt") {
+		t.Fatalf("unexpected listing %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestStaticSPA(t *testing.T) {
+	r := New()
+	r.GET("/api/ping", func(c *Context) { c.String(http.StatusOK, "pong") })
+	r.StaticFSWithConfig("/app", staticFS(), StaticConfig{SPA: true, CacheControl: "max-age=60"})
+
+	for _, path := range []string{"/app", "/app/", "/app/users/1"} {
+		w := serveStatic(r, path, nil)
+		if path == "/app" {
+			if w.Code != http.StatusMovedPermanently {
+				t.Errorf("%s should redirect to the slash path, got %d", path, w.Code)
+			}
+			continue
+		}
+		if w.Code != http.StatusOK || w.Body.String() != "<h1>app</h1>" || w.Header().Get("Cache-Control") != "no-cache" {
+			t.Errorf("%s should fall back to index.html, got %d %v %q", path, w.Code, w.Header(), w.Body.String())
+		}
+	}
+	if w := serveStatic(r, "/app/assets/missing.js", nil); w.Code != http.StatusNotFound {
+		t.Errorf("missing assets should still be 404, got %d", w.Code)
+	}
+	if w := serveStatic(r, "/app/assets/app.js", nil); w.Header().Get("Cache-Control") != "max-age=60" {
+		t.Errorf("assets should use the configured Cache-Control, got %v", w.Header())
+	}
+	if w := serveStatic(r, "/api/ping", nil); w.Body.String() != "pong" {
+		t.Errorf("other routes should not be affected, got %q", w.Body.String())
+	}
+}
+
+func TestStaticFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "robots.txt"), []byte("User-agent: *"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := New()
+	r.StaticFile("/robots.txt", filepath.Join(dir, "robots.txt"))
+	r.Static("/files", dir)
+
+	for _, path := range []string{"/robots.txt", "/files/robots.txt"} {
+		w := serveStatic(r, path, nil)
+		if w.Code != http.StatusOK || w.Body.String() != "User-agent: *" || w.Header().Get("ETag") == "" {
+			t.Errorf("%s: unexpected response %d %q", path, w.Code, w.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodHead, "/robots.txt", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Body.Len() != 0 {
+		t.Fatalf("HEAD should be served without a body, got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,322 @@
+package mygee
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+/*
+	html模板
+	LoadHtmlGlob: 所有文件解析为一个全局模板集合,按模板名渲染
+	LoadTemplates: 从fs.FS加载,每个页面文件和布局、局部模板单独组成一个模板集合,
+	页面之间可以定义同名的block(例如content)而互不影响,按页面的相对路径渲染
+	模板在第一次渲染或者Run启动服务时才解析,所以SetFuncMap可以在加载前后任意时刻调用
+	TemplateReload打开时模板文件变化后自动重新解析
+*/
+
+// TemplateSet 一组共享布局的页面
+type TemplateSet struct {
+	// Layouts 布局和局部模板的glob,每个页面都会包含这些文件
+	Layouts []string
+	// Pages 页面模板的glob,每个页面单独组成一个模板集合,以相对路径命名
+	Pages []string
+	// Entry 渲染时执行的模板名,通常是布局中定义的模板,为空时执行页面文件本身
+	Entry string
+}
+
+// templateLoader 记录模板的来源,修改FuncMap或文件变化时按原样重新加载
+type templateLoader struct {
+	glob string // LoadHtmlGlob的参数,fsys为nil时使用
+	fsys fs.FS
+	set  TemplateSet
+}
+
+// templatePage 一个页面对应的模板集合
+type templatePage struct {
+	tmpl  *template.Template
+	entry string
+}
+
+type htmlRender struct {
+	mu      sync.RWMutex
+	funcMap template.FuncMap
+	loaders []templateLoader
+	global  *template.Template
+	pages   map[string]*templatePage
+	stamp   string // 所有模板文件的名称、大小和修改时间,用于检测变化
+	dirty   bool   // 加载了新的模板或修改了FuncMap,需要重新解析
+}
+
+// SetFuncMap 设置模板函数,已经加载的模板会用新的函数重新解析
+func (e *Engine) SetFuncMap(funcMap template.FuncMap) {
+	r := e.html
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.funcMap = funcMap
+	r.dirty = len(r.loaders) > 0
+}
+
+// LoadHtmlGlob
+// 加载所有静态文件到模板中
+func (e *Engine) LoadHtmlGlob(pattern string) {
+	e.html.add(templateLoader{glob: pattern})
+}
+
+// LoadTemplates
+// 从fsys加载一组页面,解析错误在第一次渲染时交给ErrorHandler,或者由Run返回
+//
+//	r.LoadTemplates(os.DirFS("templates"), mygee.TemplateSet{
+//		Layouts: []string{"layouts/*.html", "partials/*.html"},
+//		Pages:   []string{"pages/*.html"},
+//		Entry:   "base",
+//	})
+//	c.HTML(http.StatusOK, "pages/index.html", data)
+func (e *Engine) LoadTemplates(fsys fs.FS, set TemplateSet) {
+	if len(set.Pages) == 0 {
+		panic("mygee: template set requires at least one page pattern")
+	}
+	e.html.add(templateLoader{fsys: fsys, set: set})
+}
+
+func (r *htmlRender) add(l templateLoader) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.loaders = append(r.loaders, l)
+	r.dirty = true
+}
+
+// prepare 解析还没有解析的模板
+func (r *htmlRender) prepare() error {
+	r.mu.RLock()
+	dirty := r.dirty
+	r.mu.RUnlock()
+	if !dirty {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.dirty {
+		return nil
+	}
+	return r.reload()
+}
+
+// reload 重新解析所有模板,调用方需要持有写锁,出错时保留原来的模板
+func (r *htmlRender) reload() error {
+	var global *template.Template
+	pages := make(map[string]*templatePage)
+	for _, l := range r.loaders {
+		if l.fsys == nil {
+			t, err := template.New("").Funcs(r.funcMap).ParseGlob(l.glob)
+			if err != nil {
+				return fmt.Errorf("mygee: load templates %q: %w", l.glob, err)
+			}
+			if global == nil {
+				global = t
+				continue
+			}
+			for _, sub := range t.Templates() {
+				if _, err := global.AddParseTree(sub.Name(), sub.Tree); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if err := l.loadPages(r.funcMap, pages); err != nil {
+			return err
+		}
+	}
+
+	stamp, err := r.fingerprint()
+	if err != nil {
+		return err
+	}
+	r.global, r.pages, r.stamp, r.dirty = global, pages, stamp, false
+	return nil
+}
+
+// files 返回loader匹配到的文件,layouts在前,结果已经去重
+func (l templateLoader) files() (layouts, pages []string, err error) {
+	if l.fsys == nil {
+		matches, err := filepath.Glob(l.glob)
+		return nil, matches, err
+	}
+	if layouts, err = globFS(l.fsys, l.set.Layouts); err != nil {
+		return nil, nil, err
+	}
+	all, err := globFS(l.fsys, l.set.Pages)
+	if err != nil {
+		return nil, nil, err
+	}
+	isLayout := make(map[string]bool, len(layouts))
+	for _, name := range layouts {
+		isLayout[name] = true
+	}
+	for _, name := range all {
+		if !isLayout[name] {
+			pages = append(pages, name)
+		}
+	}
+	return layouts, pages, nil
+}
+
+func globFS(fsys fs.FS, patterns []string) ([]string, error) {
+	seen := make(map[string]bool)
+	res := make([]string, 0)
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range matches {
+			if !seen[name] {
+				seen[name] = true
+				res = append(res, name)
+			}
+		}
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+func (l templateLoader) loadPages(funcMap template.FuncMap, pages map[string]*templatePage) error {
+	layouts, names, err := l.files()
+	if err != nil {
+		return err
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("mygee: template patterns %q match no pages", l.set.Pages)
+	}
+
+	// 布局只读取一次,每个页面在其副本上解析
+	base := template.New("").Funcs(funcMap)
+	for _, name := range layouts {
+		if err := parseFile(base, l.fsys, name); err != nil {
+			return err
+		}
+	}
+	for _, name := range names {
+		if _, ok := pages[name]; ok {
+			return fmt.Errorf("mygee: template page %q is loaded twice", name)
+		}
+		t, err := base.Clone()
+		if err != nil {
+			return err
+		}
+		if err := parseFile(t, l.fsys, name); err != nil {
+			return err
+		}
+		entry := l.set.Entry
+		if entry == "" {
+			entry = path.Base(name)
+		}
+		if t.Lookup(entry) == nil {
+			return fmt.Errorf("mygee: template %q is not defined for page %q", entry, name)
+		}
+		pages[name] = &templatePage{tmpl: t, entry: entry}
+	}
+	return nil
+}
+
+// parseFile 以文件名(不含目录)作为模板名解析文件,与template.ParseFiles一致
+func parseFile(t *template.Template, fsys fs.FS, name string) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	if _, err := t.New(path.Base(name)).Parse(string(data)); err != nil {
+		return fmt.Errorf("mygee: parse template %q: %w", name, err)
+	}
+	return nil
+}
+
+// fingerprint 调用方需要持有锁
+func (r *htmlRender) fingerprint() (string, error) {
+	var b strings.Builder
+	for _, l := range r.loaders {
+		layouts, pages, err := l.files()
+		if err != nil {
+			return "", err
+		}
+		for _, name := range append(layouts, pages...) {
+			var info fs.FileInfo
+			if l.fsys == nil {
+				info, err = os.Stat(name)
+			} else {
+				info, err = fs.Stat(l.fsys, name)
+			}
+			if err != nil {
+				return "", err
+			}
+			fmt.Fprintf(&b, "%s|%d|%d\n", name, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return b.String(), nil
+}
+
+// reloadIfChanged 开发模式下每次渲染前检查模板文件是否变化
+func (r *htmlRender) reloadIfChanged() error {
+	r.mu.RLock()
+	stamp, err := r.fingerprint()
+	changed := err != nil || stamp != r.stamp || r.dirty
+	r.mu.RUnlock()
+	if !changed {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reload()
+}
+
+// render 渲染到buf,name先按页面查找,找不到时在全局模板集合中查找
+func (r *htmlRender) render(buf *bytes.Buffer, name string, data interface{}) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if p, ok := r.pages[name]; ok {
+		return p.tmpl.ExecuteTemplate(buf, p.entry, data)
+	}
+	if r.global == nil {
+		return fmt.Errorf("mygee: template %q is not loaded", name)
+	}
+	return r.global.ExecuteTemplate(buf, name, data)
+}
+
+var renderBufPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// HTML
+// 先渲染到缓冲区再写出,模板执行出错时不会写出半个页面,错误交给ErrorHandler以500响应
+func (c *Context) HTML(code int, name string, data interface{}) {
+	r := c.e.html
+	prepare := r.prepare
+	if c.e.TemplateReload {
+		prepare = r.reloadIfChanged
+	}
+	if err := prepare(); err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	buf := renderBufPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer renderBufPool.Put(buf)
+	if err := r.render(buf, name, data); err != nil {
+		c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+	c.SetHeader("Content-Type", "text/html; charset=utf-8")
+	c.Status(code)
+	c.W.Write(buf.Bytes())
+}
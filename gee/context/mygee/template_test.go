@@ -0,0 +1,109 @@
+package mygee
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func renderPage(r *Engine, name string, data interface{}) *httptest.ResponseRecorder {
+	r.GET("/"+name, func(c *Context) {
+		c.HTML(http.StatusOK, name, data)
+	})
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/"+name, nil))
+	return w
+}
+
+func TestTemplateLayouts(t *testing.T) {
+	fsys := fstest.MapFS{
+		"layouts/base.html":  {Data: []byte(`{{define "base"}}<title>{{block "title" .}}gee{{end}}</title>{{template "nav"}}{{template "content" .}}{{end}}`)},
+		"partials/nav.html":  {Data: []byte(`{{define "nav"}}<nav>{{upper "home"}}</nav>{{end}}`)},
+		"pages/index.html":   {Data: []byte(`{{define "content"}}<p>{{.}}</p>{{end}}`)},
+		"pages/about.html":   {Data: []byte(`{{define "title"}}about{{end}}{{define "content"}}<p>about {{.}}</p>{{end}}`)},
+		"pages/broken.html":  {Data: []byte(`{{define "content"}}{{.Missing.Field}}{{end}}`)},
+		"admin/console.html": {Data: []byte(`<h1>{{.}}</h1>`)},
+	}
+
+	r := New()
+	r.Use(ErrorHandler())
+	r.LoadTemplates(fsys, TemplateSet{
+		Layouts: []string{"layouts/*.html", "partials/*.html"},
+		Pages:   []string{"pages/*.html"},
+		Entry:   "base",
+	})
+	r.LoadTemplates(fsys, TemplateSet{Pages: []string{"admin/*.html"}})
+	// 加载之后再设置函数也会生效
+	r.SetFuncMap(template.FuncMap{"upper": strings.ToUpper})
+
+	testCases := []struct {
+		page, body string
+	}{
+		{"pages/index.html", "<title>gee</title><nav>HOME</nav><p>&lt;gee&gt;</p>"},
+		{"pages/about.html", "<title>about</title><nav>HOME</nav><p>about &lt;gee&gt;</p>"},
+		{"admin/console.html", "<h1>&lt;gee&gt;</h1>"},
+	}
+	for _, tc := range testCases {
+		w := renderPage(r, tc.page, "<gee>")
+		if w.Code != http.StatusOK || w.Body.String() != tc.body {
+			t.Errorf("%s: unexpected response %d %q", tc.page, w.Code, w.Body.String())
+		}
+	}
+
+	w := renderPage(r, "pages/broken.html", "gee")
+	if w.Code != http.StatusInternalServerError || w.Header().Get("Content-Type") != MIMEProblemJSON ||
+		strings.Contains(w.Body.String(), "<title>") {
+		t.Fatalf("template errors should be handled by ErrorHandler, got %d %q", w.Code, w.Body.String())
+	}
+	if w := renderPage(r, "pages/missing.html", nil); w.Code != http.StatusInternalServerError {
+		t.Fatalf("missing template should be 500, got %d", w.Code)
+	}
+}
+
+func TestTemplateReload(t *testing.T) {
+	dir := t.TempDir()
+	page := filepath.Join(dir, "index.html")
+	write := func(content string, mtime time.Time) {
+		if err := os.WriteFile(page, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		os.Chtimes(page, mtime, mtime)
+	}
+	now := time.Now()
+	write("v1 {{.}}", now)
+
+	r := New()
+	r.LoadTemplates(os.DirFS(dir), TemplateSet{Pages: []string{"*.html"}})
+	r.LoadHtmlGlob(filepath.Join(dir, "*.html"))
+	r.GET("/", func(c *Context) { c.HTML(http.StatusOK, "index.html", "gee") })
+	get := func() string {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		return w.Body.String()
+	}
+
+	if body := get(); body != "v1 gee" {
+		t.Fatalf("unexpected body %q", body)
+	}
+	write("v2 {{.}}", now.Add(time.Second))
+	if body := get(); body != "v1 gee" {
+		t.Fatalf("templates should not reload by default, got %q", body)
+	}
+	r.TemplateReload = true
+	if body := get(); body != "v2 gee" {
+		t.Fatalf("templates should reload after change, got %q", body)
+	}
+
+	write("v3 {{", now.Add(2*time.Second))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("parse errors in dev mode should be 500, got %d %q", w.Code, w.Body.String())
+	}
+}
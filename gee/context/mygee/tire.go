@@ -1,70 +1,212 @@
 package mygee
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
+
+// 压缩前缀树(radix tree)
+// 静态部分按公共前缀压缩存放在children中,参数(:name)和通配(*name)各自单独挂在paramChild和catchChild上
+// 匹配时优先级: 静态 > 参数 > 通配,失败时回溯尝试下一优先级
+type nodeType uint8
+
+const (
+	static nodeType = iota
+	param
+	catchAll
+)
 
 type node struct {
-	pattern  string
-	part     string
-	children []*node
-	isWild   bool
-}
+	path       string // 静态节点为压缩后的路径片段,参数节点为":name",通配节点为"*name"
+	nType      nodeType
+	pattern    string    // 完整的路由规则,非空表示该节点是一个路由的终点
+	paramNames []string  // 路由中参数的名字,与匹配时得到的参数一一对应
+	name       string    // 通过Route.Name设置的路由名
+	doc        *routeDoc // 通过Route.Summary等方法设置的文档信息,用于生成OpenAPI文档
 
-func (n *node) matchChild(part string) *node {
+	group    *RouterGroup  // 注册该路由的分组
+	handler  HandlerFunc   // 注册时传入的处理函数
+	handlers []HandlerFunc // 分组中间件+处理函数组装好的处理链
 
-	for _, child := range n.children {
-		if child.part == part || child.isWild {
-			return child
+	indices    string  // 静态子节点path的首字节,与children一一对应
+	children   []*node // 静态子节点
+	paramChild *node
+	catchChild *node
+}
+
+// cleanPath 规范化路径: 以'/'开头,去掉重复的'/'和末尾的'/'
+// 已经规范的路径原样返回,不产生内存分配
+func cleanPath(p string) string {
+	if isCleanPath(p) {
+		return p
+	}
+	var b strings.Builder
+	for _, part := range strings.Split(p, "/") {
+		if part == "" {
+			continue
 		}
+		b.WriteByte('/')
+		b.WriteString(part)
+	}
+	if b.Len() == 0 {
+		return "/"
 	}
-	return nil
+	return b.String()
 }
 
-func (n *node) matchChildren(part string) []*node {
-	res := make([]*node, 0)
+func isCleanPath(p string) bool {
+	if p == "/" {
+		return true
+	}
+	if p == "" || p[0] != '/' || p[len(p)-1] == '/' {
+		return false
+	}
+	return !strings.Contains(p, "//")
+}
 
-	for _, child := range n.children {
-		if child.part == part || child.isWild {
-			res = append(res, child)
+// insert 将规范化后的路由规则插入树中并返回终点节点,规则冲突时直接panic
+func (n *node) insert(pattern string) *node {
+	path := pattern
+	var names []string
+
+	for {
+		if path == "" {
+			if n.pattern != "" {
+				panic(fmt.Sprintf("mygee: route %q conflicts with existing route %q", pattern, n.pattern))
+			}
+			n.pattern = pattern
+			n.paramNames = names
+			return n
+		}
+
+		if path[0] == ':' || path[0] == '*' {
+			end := strings.IndexByte(path, '/')
+			if end < 0 {
+				end = len(path)
+			}
+			wild := path[:end]
+			if len(wild) < 2 {
+				panic(fmt.Sprintf("mygee: wildcard in route %q must be named", pattern))
+			}
+			if wild[0] == '*' && end != len(path) {
+				panic(fmt.Sprintf("mygee: catch-all %q must be the last segment in route %q", wild, pattern))
+			}
+			names = append(names, wild[1:])
+
+			child := &n.paramChild
+			nType := param
+			if wild[0] == '*' {
+				child = &n.catchChild
+				nType = catchAll
+			}
+			if *child == nil {
+				*child = &node{path: wild, nType: nType}
+			} else if (*child).path != wild {
+				panic(fmt.Sprintf("mygee: %q in route %q conflicts with existing wildcard %q", wild, pattern, (*child).path))
+			}
+			n = *child
+			path = path[end:]
+			continue
+		}
+
+		// 静态部分,截止到下一个通配符
+		end := strings.IndexAny(path, ":*")
+		if end < 0 {
+			end = len(path)
+		} else if path[end-1] != '/' {
+			panic(fmt.Sprintf("mygee: wildcard must start a path segment in route %q", pattern))
 		}
+		n = n.insertStatic(path[:end])
+		path = path[end:]
 	}
-	return res
 }
 
-func (n *node) insert(pattern string, parts []string, height int) {
-	if len(parts) == height {
-		n.pattern = pattern
-		return
+// insertStatic 沿静态子节点插入一段静态路径,必要时分裂已有节点,返回该段路径的终点节点
+func (n *node) insertStatic(path string) *node {
+	for path != "" {
+		i := strings.IndexByte(n.indices, path[0])
+		if i < 0 {
+			child := &node{path: path}
+			n.indices += string(path[0])
+			n.children = append(n.children, child)
+			return child
+		}
+
+		child := n.children[i]
+		l := longestCommonPrefix(path, child.path)
+		if l < len(child.path) {
+			// 分裂: child拆成公共前缀和剩余部分
+			rest := *child
+			rest.path = child.path[l:]
+			*child = node{
+				path:     child.path[:l],
+				indices:  string(rest.path[0]),
+				children: []*node{&rest},
+			}
+		}
+		n = child
+		path = path[l:]
 	}
+	return n
+}
 
-	part := parts[height]
-	child := n.matchChild(part)
+// walk 遍历子树中所有路由终点节点
+func (n *node) walk(fn func(n *node)) {
+	if n.pattern != "" {
+		fn(n)
+	}
+	for _, child := range n.children {
+		child.walk(fn)
+	}
+	if n.paramChild != nil {
+		n.paramChild.walk(fn)
+	}
+	if n.catchChild != nil {
+		n.catchChild.walk(fn)
+	}
+}
 
-	if child == nil {
-		child = &node{
-			part:   part,
-			isWild: part[0] == ':' || part[0] == '*',
-		}
-		n.children = append(n.children, child)
+func longestCommonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
 	}
-	child.insert(pattern, parts, height+1)
+	return i
 }
 
-func (n *node) search(parts []string, height int) *node {
-	if len(parts) == height || strings.HasPrefix(n.part, "*") {
-		if n.pattern == "" {
-			return nil
+// search 在当前节点之下匹配剩余的path,params按顺序收集匹配到的参数值,参数名由调用方根据paramNames填充
+// 返回匹配到的终点节点,没有匹配时返回nil
+func (n *node) search(path string, params Params) (*node, Params) {
+	if path == "" {
+		if n.pattern != "" {
+			return n, params
 		}
-		return n
+		return nil, params
 	}
 
-	part := parts[height]
-	children := n.matchChildren(part)
+	if i := strings.IndexByte(n.indices, path[0]); i >= 0 {
+		child := n.children[i]
+		if strings.HasPrefix(path, child.path) {
+			if res, p := child.search(path[len(child.path):], params); res != nil {
+				return res, p
+			}
+		}
+	}
 
-	for _, child := range children {
-		res := child.search(parts, height+1)
-		if res != nil {
-			return res
+	if n.paramChild != nil {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end > 0 {
+			if res, p := n.paramChild.search(path[end:], append(params, Param{Value: path[:end]})); res != nil {
+				return res, p
+			}
 		}
 	}
-	return nil
+
+	if n.catchChild != nil && n.catchChild.pattern != "" {
+		return n.catchChild, append(params, Param{Value: path})
+	}
+	return nil, params
 }
@@ -0,0 +1,235 @@
+package mygee
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSearchPriority(t *testing.T) {
+	root := &node{}
+	for _, p := range []string{"/user/:id", "/user/me", "/user/*rest", "/user/:id/posts", "/static/*filepath", "/"} {
+		root.insert(p)
+	}
+
+	testCases := map[string]string{
+		"/":                  "/",
+		"/user/me":           "/user/me",
+		"/user/mex":          "/user/:id",
+		"/user/1":            "/user/:id",
+		"/user/me/posts":     "/user/:id/posts",
+		"/user/1/comments":   "/user/*rest",
+		"/static/css/a.css":  "/static/*filepath",
+		"/static/index.html": "/static/*filepath",
+	}
+	for path, pattern := range testCases {
+		n, _ := root.search(path, nil)
+		if n == nil || n.pattern != pattern {
+			t.Errorf("searching %s, should have matched %s", path, pattern)
+		}
+	}
+
+	n, params := root.search("/static/css/a.css", nil)
+	if n.paramNames[0] != "filepath" || params[0].Value != "css/a.css" {
+		t.Fatalf("catch-all param failed: %v=%v", n.paramNames, params)
+	}
+	if n, _ := root.search("/static", nil); n != nil {
+		t.Fatalf("/static should not match %s", n.pattern)
+	}
+}
+
+func TestInsertConflict(t *testing.T) {
+	conflicts := [][]string{
+		{"/a/:x", "/a/:y"},
+		{"/a/*x", "/a/*y"},
+		{"/a/:x/b", "/a/:y/c"},
+		{"/a/b", "/a/b"},
+		{"/a/*x/b"},
+		{"/a/b:c"},
+	}
+	for _, patterns := range conflicts {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("inserting %v should have panicked", patterns)
+				}
+			}()
+			root := &node{}
+			for _, p := range patterns {
+				root.insert(p)
+			}
+		}()
+	}
+}
+
+func TestCleanPath(t *testing.T) {
+	testCases := map[string]string{
+		"":         "/",
+		"/":        "/",
+		"/a/b":     "/a/b",
+		"a/b/":     "/a/b",
+		"//a///b/": "/a/b",
+	}
+	for p, want := range testCases {
+		if got := cleanPath(p); got != want {
+			t.Errorf("cleanPath(%q) = %q, want %q", p, got, want)
+		}
+	}
+}
+
+// legacyNode 是替换为radix tree之前按'/'分段的前缀树,仅用于基准测试对比
+type legacyNode struct {
+	pattern  string
+	part     string
+	children []*legacyNode
+	isWild   bool
+}
+
+func legacyParsePatterns(pattern string) []string {
+	res := make([]string, 0)
+	for _, part := range strings.Split(pattern, "/") {
+		if part == "" {
+			continue
+		}
+		res = append(res, part)
+		if part[0] == '*' {
+			break
+		}
+	}
+	return res
+}
+
+func (n *legacyNode) matchChild(part string) *legacyNode {
+	for _, child := range n.children {
+		if child.part == part || child.isWild {
+			return child
+		}
+	}
+	return nil
+}
+
+func (n *legacyNode) matchChildren(part string) []*legacyNode {
+	res := make([]*legacyNode, 0)
+	for _, child := range n.children {
+		if child.part == part || child.isWild {
+			res = append(res, child)
+		}
+	}
+	return res
+}
+
+func (n *legacyNode) insert(pattern string, parts []string, height int) {
+	if len(parts) == height {
+		n.pattern = pattern
+		return
+	}
+	part := parts[height]
+	child := n.matchChild(part)
+	if child == nil {
+		child = &legacyNode{part: part, isWild: part[0] == ':' || part[0] == '*'}
+		n.children = append(n.children, child)
+	}
+	child.insert(pattern, parts, height+1)
+}
+
+func (n *legacyNode) search(parts []string, height int) *legacyNode {
+	if len(parts) == height || strings.HasPrefix(n.part, "*") {
+		if n.pattern == "" {
+			return nil
+		}
+		return n
+	}
+	for _, child := range n.matchChildren(parts[height]) {
+		if res := child.search(parts, height+1); res != nil {
+			return res
+		}
+	}
+	return nil
+}
+
+// legacyGetRoute 与旧版router.getRoute的逻辑一致: 匹配后重新解析pattern并构造参数map
+func legacyGetRoute(root *legacyNode, path string) (*legacyNode, map[string]string) {
+	searchParts := legacyParsePatterns(path)
+	n := root.search(searchParts, 0)
+	if n == nil {
+		return nil, nil
+	}
+	params := make(map[string]string)
+	for index, part := range legacyParsePatterns(n.pattern) {
+		if part[0] == ':' && len(part) > 1 {
+			params[part[1:]] = searchParts[index]
+		}
+		if part[0] == '*' && len(part) > 1 {
+			params[part[1:]] = strings.Join(searchParts[index:], "/")
+		}
+	}
+	return n, params
+}
+
+var benchResources = []string{
+	"users", "orgs", "repos", "teams", "projects", "issues", "pulls", "comments",
+	"labels", "milestones", "releases", "assets", "hooks", "keys", "gists", "events",
+	"notifications", "packages", "actions", "runners", "secrets", "deployments",
+	"environments", "checks", "statuses", "branches", "tags", "commits", "invitations", "licenses",
+}
+
+// benchRoutes 生成约300条接近真实API规模的路由,以及对应的请求路径
+func benchRoutes() (patterns []string, paths []string) {
+	for _, res := range benchResources {
+		patterns = append(patterns,
+			"/api/v1/"+res,
+			"/api/v1/"+res+"/search",
+			"/api/v1/"+res+"/:id",
+			"/api/v1/"+res+"/:id/history",
+			"/api/v1/"+res+"/:id/members",
+			"/api/v1/"+res+"/:id/members/:member",
+			"/api/v1/"+res+"/:id/settings",
+			"/api/v1/"+res+"/:id/settings/:key",
+			"/api/v1/"+res+"/:id/files/*filepath",
+			"/api/v2/"+res+"/:id",
+		)
+		paths = append(paths,
+			"/api/v1/"+res,
+			"/api/v1/"+res+"/search",
+			"/api/v1/"+res+"/42/members/7",
+			"/api/v1/"+res+"/42/files/docs/readme.md",
+		)
+	}
+	return
+}
+
+func BenchmarkRadixTree(b *testing.B) {
+	patterns, paths := benchRoutes()
+	r := newRouter()
+	r.root["GET"] = &node{}
+	for _, p := range patterns {
+		r.root["GET"].insert(p)
+	}
+	var params Params
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, p := range paths {
+			var n *node
+			if n, params = r.getRoute("GET", p, params); n == nil {
+				b.Fatalf("%s not matched", p)
+			}
+		}
+	}
+}
+
+func BenchmarkLegacyTrie(b *testing.B) {
+	patterns, paths := benchRoutes()
+	root := &legacyNode{}
+	for _, p := range patterns {
+		root.insert(p, legacyParsePatterns(p), 0)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, p := range paths {
+			if n, _ := legacyGetRoute(root, p); n == nil {
+				b.Fatalf("%s not matched", p)
+			}
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package mygee
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+/*
+	请求id与W3C trace context的透传
+	RequestID中间件读取或生成X-Request-ID,解析或生成traceparent,保存在c.Keys中并写回响应头
+	访问日志和Recovery会自动带上这两个id
+	调用下游时通过InjectTrace把它们写入请求头,或者通过TraceMetadata取出后随rpc参数一起发送
+*/
+
+const (
+	// RequestIDKey 请求id在Keys中的key
+	RequestIDKey = "mygee.request_id"
+	// TraceKey TraceContext在Keys中的key
+	TraceKey = "mygee.trace"
+
+	HeaderRequestID   = "X-Request-ID"
+	HeaderTraceParent = "traceparent"
+	HeaderTraceState  = "tracestate"
+)
+
+// maxRequestIDLength 上游传入的请求id超过该长度或包含不可见字符时重新生成,避免污染日志
+const maxRequestIDLength = 128
+
+var errInvalidTraceParent = errors.New("mygee: invalid traceparent")
+
+// TraceContext W3C trace context
+type TraceContext struct {
+	TraceID  string // 32位十六进制
+	ParentID string // 上游的span id,新建的trace为空
+	SpanID   string // 本次请求的span id,16位十六进制
+	Flags    byte   // trace-flags,最低位表示是否采样
+	State    string // tracestate,原样透传
+}
+
+// TraceParent 返回以本次请求的span为父节点的traceparent,用于调用下游
+func (t TraceContext) TraceParent() string {
+	const hexDigits = "0123456789abcdef"
+	return "00-" + t.TraceID + "-" + t.SpanID + "-" + string([]byte{hexDigits[t.Flags>>4], hexDigits[t.Flags&0x0f]})
+}
+
+func (t TraceContext) Sampled() bool {
+	return t.Flags&0x01 == 0x01
+}
+
+// ParseTraceParent
+// 解析traceparent请求头,返回值的SpanID为空,ParentID为上游的span id
+func ParseTraceParent(s string) (TraceContext, error) {
+	s = strings.TrimSpace(s)
+	// version-traceid-parentid-flags,高版本允许在后面追加字段
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' || (len(s) > 55 && s[55] != '-') {
+		return TraceContext{}, errInvalidTraceParent
+	}
+	version, traceID, parentID, flags := s[0:2], s[3:35], s[36:52], s[53:55]
+	if !isLowerHex(version) || version == "ff" || (version == "00" && len(s) != 55) {
+		return TraceContext{}, errInvalidTraceParent
+	}
+	if !isLowerHex(traceID) || isZeroHex(traceID) || !isLowerHex(parentID) || isZeroHex(parentID) || !isLowerHex(flags) {
+		return TraceContext{}, errInvalidTraceParent
+	}
+	f, _ := hex.DecodeString(flags)
+	return TraceContext{TraceID: traceID, ParentID: parentID, Flags: f[0]}, nil
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !('0' <= s[i] && s[i] <= '9' || 'a' <= s[i] && s[i] <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+func isZeroHex(s string) bool {
+	return strings.Trim(s, "0") == ""
+}
+
+func randomHex(n int) string {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		panic("mygee: read random bytes: " + err.Error())
+	}
+	return hex.EncodeToString(buf)
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] >= 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestIDConfig 请求id中间件的配置
+type RequestIDConfig struct {
+	// Header 请求id使用的请求头,默认为X-Request-ID
+	Header string
+	// Generator 生成新的请求id,默认为32位随机十六进制
+	Generator func() string
+}
+
+// RequestID
+// 使用默认配置透传请求id和traceparent
+func RequestID() HandlerFunc {
+	return RequestIDWithConfig(RequestIDConfig{})
+}
+
+func RequestIDWithConfig(conf RequestIDConfig) HandlerFunc {
+	if conf.Header == "" {
+		conf.Header = HeaderRequestID
+	}
+	if conf.Generator == nil {
+		conf.Generator = func() string { return randomHex(16) }
+	}
+
+	return func(c *Context) {
+		id := c.Req.Header.Get(conf.Header)
+		if !validRequestID(id) {
+			id = conf.Generator()
+		}
+
+		tc, err := ParseTraceParent(c.Req.Header.Get(HeaderTraceParent))
+		if err == nil {
+			tc.State = c.Req.Header.Get(HeaderTraceState)
+		} else {
+			// 无效的traceparent连同tracestate一起丢弃,开始新的trace
+			tc = TraceContext{TraceID: randomHex(16), Flags: 0x01}
+		}
+		tc.SpanID = randomHex(8)
+
+		c.Set(RequestIDKey, id)
+		c.Set(TraceKey, tc)
+		h := c.W.Header()
+		h.Set(conf.Header, id)
+		h.Set(HeaderTraceParent, tc.TraceParent())
+		c.Next()
+	}
+}
+
+// RequestID 返回RequestID中间件保存的请求id,没有使用中间件时为空
+func (c *Context) RequestID() string {
+	return c.GetString(RequestIDKey)
+}
+
+// Trace 返回RequestID中间件保存的trace context
+func (c *Context) Trace() (TraceContext, bool) {
+	v, _ := c.Get(TraceKey)
+	tc, ok := v.(TraceContext)
+	return tc, ok
+}
+
+// RequestIDFromContext
+// 从context中取出请求id,ctx可以是*Context或者由它派生的context
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(RequestIDKey).(string)
+	return id
+}
+
+// TraceFromContext 从context中取出trace context
+func TraceFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(TraceKey).(TraceContext)
+	return tc, ok
+}
+
+// TraceMetadata
+// 返回需要透传给下游的请求头,适用于没有http请求头的调用方式,例如geeRpc
+func TraceMetadata(ctx context.Context) map[string]string {
+	md := make(map[string]string, 3)
+	if id := RequestIDFromContext(ctx); id != "" {
+		md[HeaderRequestID] = id
+	}
+	if tc, ok := TraceFromContext(ctx); ok {
+		md[HeaderTraceParent] = tc.TraceParent()
+		if tc.State != "" {
+			md[HeaderTraceState] = tc.State
+		}
+	}
+	return md
+}
+
+// InjectTrace
+// 把请求id和traceparent写入调用下游的http请求头
+//
+//	req, _ := http.NewRequestWithContext(c, http.MethodGet, url, nil)
+//	mygee.InjectTrace(c, req.Header)
+func InjectTrace(ctx context.Context, h http.Header) {
+	for k, v := range TraceMetadata(ctx) {
+		h.Set(k, v)
+	}
+}
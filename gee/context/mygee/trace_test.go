@@ -0,0 +1,102 @@
+package mygee
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseTraceParent(t *testing.T) {
+	tc, err := ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if err != nil || tc.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || tc.ParentID != "00f067aa0ba902b7" || !tc.Sampled() {
+		t.Fatalf("unexpected trace context %+v, %v", tc, err)
+	}
+	if _, err := ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra"); err != nil {
+		t.Fatalf("future versions may append fields: %v", err)
+	}
+
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}
+	for _, s := range invalid {
+		if _, err := ParseTraceParent(s); err == nil {
+			t.Errorf("%q should be invalid", s)
+		}
+	}
+}
+
+func TestRequestIDPropagation(t *testing.T) {
+	var buf bytes.Buffer
+	var md map[string]string
+	r := New()
+	r.Use(RequestID(), LoggerWithConfig(LoggerConfig{Output: &buf}))
+	r.GET("/ping", func(c *Context) {
+		ctx, cancel := context.WithCancel(c)
+		defer cancel()
+		md = TraceMetadata(ctx)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set(HeaderRequestID, "req-1")
+	req.Header.Set(HeaderTraceParent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set(HeaderTraceState, "gee=1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Header().Get(HeaderRequestID) != "req-1" {
+		t.Fatalf("request id should be echoed, got %q", w.Header().Get(HeaderRequestID))
+	}
+	tp := w.Header().Get(HeaderTraceParent)
+	tc, err := ParseTraceParent(tp)
+	if err != nil || tc.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || tc.ParentID == "00f067aa0ba902b7" {
+		t.Fatalf("response traceparent should keep the trace id with a new span, got %q", tp)
+	}
+	if md[HeaderRequestID] != "req-1" || md[HeaderTraceParent] != tp || md[HeaderTraceState] != "gee=1" {
+		t.Fatalf("unexpected metadata %v", md)
+	}
+
+	var entry accessLog
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.RequestID != "req-1" || entry.TraceID != tc.TraceID {
+		t.Fatalf("log entry should contain the ids: %+v", entry)
+	}
+}
+
+func TestRequestIDGenerate(t *testing.T) {
+	r := New()
+	r.Use(RequestID())
+	r.GET("/ping", func(c *Context) {
+		h := make(http.Header)
+		InjectTrace(c, h)
+		c.String(http.StatusOK, "%s %s", h.Get(HeaderRequestID), h.Get(HeaderTraceParent))
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	req.Header.Set(HeaderRequestID, "bad id\n")
+	req.Header.Set(HeaderTraceParent, "garbage")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	id, tp := w.Header().Get(HeaderRequestID), w.Header().Get(HeaderTraceParent)
+	if len(id) != 32 || strings.ContainsAny(id, " \n") {
+		t.Fatalf("invalid request id should be replaced, got %q", id)
+	}
+	if _, err := ParseTraceParent(tp); err != nil {
+		t.Fatalf("generated traceparent %q is invalid: %v", tp, err)
+	}
+	if w.Body.String() != id+" "+tp {
+		t.Fatalf("injected headers %q do not match response headers", w.Body.String())
+	}
+}
@@ -0,0 +1,164 @@
+package mygee
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/*
+	文件上传与请求体限制
+	multipart表单按Engine.MaxMultipartMemory解析,超过的部分由标准库写入临时文件,请求结束后自动删除
+	BodyLimit限制请求体的大小,超过时返回413; AllowContentTypes限制请求的Content-Type,不允许时返回415
+*/
+
+const defaultMultipartMemory = 32 << 20 // 32 MB
+
+// ErrBodyTooLarge 读取的请求体超过了BodyLimit设置的大小
+var ErrBodyTooLarge = errors.New("request body too large")
+
+// MultipartForm 解析并返回multipart表单,包括上传的文件
+func (c *Context) MultipartForm() (*multipart.Form, error) {
+	if err := c.parseMultipartForm(); err != nil {
+		return nil, err
+	}
+	return c.Req.MultipartForm, nil
+}
+
+// FormFile 返回表单中名为name的第一个文件
+func (c *Context) FormFile(name string) (*multipart.FileHeader, error) {
+	if err := c.parseMultipartForm(); err != nil {
+		return nil, err
+	}
+	files := c.Req.MultipartForm.File[name]
+	if len(files) == 0 {
+		return nil, http.ErrMissingFile
+	}
+	return files[0], nil
+}
+
+func (c *Context) parseMultipartForm() error {
+	if c.Req.MultipartForm != nil {
+		return nil
+	}
+	// 通过NewContext创建的Context没有关联Engine
+	maxMemory := int64(defaultMultipartMemory)
+	if c.e != nil {
+		maxMemory = c.e.MaxMultipartMemory
+	}
+	return c.Req.ParseMultipartForm(maxMemory)
+}
+
+// SaveUploadedFile
+// 把上传的文件保存到dst,目录不存在时自动创建
+// dst应该由服务端决定,不要直接使用客户端提供的文件名
+func (c *Context) SaveUploadedFile(fh *multipart.FileHeader, dst string) error {
+	src, err := fh.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0750); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// BodyLimit
+// 限制请求体最多maxBytes字节,Content-Length超过时直接返回413,
+// 没有Content-Length(分块传输)时读取超过限制的部分会得到ErrBodyTooLarge,处理链结束后同样以413响应
+func BodyLimit(maxBytes int64) HandlerFunc {
+	if maxBytes <= 0 {
+		panic("mygee: body limit must be positive")
+	}
+	return func(c *Context) {
+		if c.Req.ContentLength > maxBytes {
+			c.AbortWithError(http.StatusRequestEntityTooLarge, ErrBodyTooLarge).SetType(ErrorTypePublic)
+			return
+		}
+		if c.Req.Body == nil || c.Req.Body == http.NoBody {
+			c.Next()
+			return
+		}
+
+		body := &limitedBody{ReadCloser: c.Req.Body, remaining: maxBytes}
+		c.Req.Body = body
+		c.Next()
+
+		if body.exceeded && !c.W.Written() {
+			c.AbortWithError(http.StatusRequestEntityTooLarge, ErrBodyTooLarge).SetType(ErrorTypePublic)
+		}
+	}
+}
+
+// limitedBody 读取超过限制后返回ErrBodyTooLarge
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+	exceeded  bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.exceeded {
+		return 0, ErrBodyTooLarge
+	}
+	// 多读一个字节,用来区分恰好读完和超过限制
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		b.exceeded = true
+		n = int(b.remaining)
+		b.remaining = 0
+		return n, ErrBodyTooLarge
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// AllowContentTypes
+// 只允许指定Content-Type的请求体,例如"application/json","image/*",不允许时返回415
+// 没有请求体的请求不做检查
+func AllowContentTypes(types ...string) HandlerFunc {
+	if len(types) == 0 {
+		panic("mygee: at least one content type is required")
+	}
+	allowed := make([]string, len(types))
+	for i, typ := range types {
+		allowed[i] = strings.ToLower(typ)
+	}
+
+	return func(c *Context) {
+		if c.Req.ContentLength == 0 && (c.Req.Body == nil || c.Req.Body == http.NoBody) {
+			c.Next()
+			return
+		}
+		typ, _, err := mime.ParseMediaType(c.Req.Header.Get("Content-Type"))
+		if err == nil {
+			for _, a := range allowed {
+				if mimeMatch(a, typ) {
+					c.Next()
+					return
+				}
+			}
+		}
+		c.AbortWithError(http.StatusUnsupportedMediaType,
+			fmt.Errorf("unsupported Content-Type %q", c.Req.Header.Get("Content-Type"))).SetType(ErrorTypePublic)
+	}
+}
@@ -0,0 +1,156 @@
+package mygee
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func multipartBody(t *testing.T, field, filename string, content []byte) (*bytes.Buffer, string) {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	mw.WriteField("title", "demo")
+	fw, err := mw.CreateFormFile(field, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(content)
+	mw.Close()
+	return &buf, mw.FormDataContentType()
+}
+
+func TestFormFileAndSave(t *testing.T) {
+	dir := t.TempDir()
+	r := New()
+	r.MaxMultipartMemory = 8
+	r.POST("/upload", func(c *Context) {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		form, _ := c.MultipartForm()
+		dst := filepath.Join(dir, "nested", "upload.bin")
+		if err := c.SaveUploadedFile(fh, dst); err != nil {
+			c.String(http.StatusInternalServerError, err.Error())
+			return
+		}
+		c.String(http.StatusCreated, "%s %s %d", form.Value["title"][0], fh.Filename, fh.Size)
+	})
+
+	content := bytes.Repeat([]byte("gee"), 100)
+	body, contentType := multipartBody(t, "file", "a.bin", content)
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated || w.Body.String() != "demo a.bin 300" {
+		t.Fatalf("unexpected response %d %q", w.Code, w.Body.String())
+	}
+	saved, err := os.ReadFile(filepath.Join(dir, "nested", "upload.bin"))
+	if err != nil || !bytes.Equal(saved, content) {
+		t.Fatalf("saved file mismatch: %v", err)
+	}
+
+	body, contentType = multipartBody(t, "other", "a.bin", content)
+	req = httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set("Content-Type", contentType)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "no such file") {
+		t.Fatalf("missing file should be reported, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+// 通过NewContext创建的Context没有Engine,使用默认的内存限制
+func TestFormFileWithoutEngine(t *testing.T) {
+	body, contentType := multipartBody(t, "file", "a.bin", []byte("gee"))
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set("Content-Type", contentType)
+	c := NewContext(httptest.NewRecorder(), req)
+	fh, err := c.FormFile("file")
+	if err != nil || fh.Filename != "a.bin" {
+		t.Fatalf("FormFile failed: %v", err)
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	r := New()
+	r.Use(ErrorHandler(), BodyLimit(10))
+	r.POST("/read", func(c *Context) {
+		data, err := io.ReadAll(c.Req.Body)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.String(http.StatusOK, "%d", len(data))
+	})
+	r.POST("/bind", func(c *Context) {
+		var form struct {
+			Name string `form:"name"`
+		}
+		c.Bind(&form)
+	})
+
+	testCases := []struct {
+		path, body string
+		chunked    bool
+		code       int
+	}{
+		{"/read", "0123456789", false, http.StatusOK},
+		{"/read", "0123456789a", false, http.StatusRequestEntityTooLarge},
+		{"/read", "0123456789", true, http.StatusOK},
+		{"/read", "0123456789a", true, http.StatusRequestEntityTooLarge},
+		{"/bind", "name=0123456789", true, http.StatusRequestEntityTooLarge},
+	}
+	for _, tc := range testCases {
+		req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if tc.chunked {
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.code {
+			t.Errorf("%s %q chunked=%v: expected %d, got %d %s", tc.path, tc.body, tc.chunked, tc.code, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestAllowContentTypes(t *testing.T) {
+	r := New()
+	r.Use(AllowContentTypes("application/json", "image/*"))
+	r.Any("/media", func(c *Context) {})
+
+	testCases := []struct {
+		method, contentType, body string
+		code                      int
+	}{
+		{http.MethodPost, "application/json; charset=utf-8", "{}", http.StatusOK},
+		{http.MethodPut, "image/png", "png", http.StatusOK},
+		{http.MethodPost, "text/plain", "hi", http.StatusUnsupportedMediaType},
+		{http.MethodPost, "", "hi", http.StatusUnsupportedMediaType},
+		{http.MethodGet, "", "", http.StatusOK},
+	}
+	for _, tc := range testCases {
+		var body io.Reader
+		if tc.body != "" {
+			body = strings.NewReader(tc.body)
+		}
+		req := httptest.NewRequest(tc.method, "/media", body)
+		if tc.contentType != "" {
+			req.Header.Set("Content-Type", tc.contentType)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tc.code {
+			t.Errorf("%s %q: expected %d, got %d", tc.method, tc.contentType, tc.code, w.Code)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+package mygee
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+/*
+	参数校验: 通过validate tag声明规则,多条规则用逗号分隔
+	required       非零值
+	min=N / max=N  字符串按字符数,切片和map按长度,数字按数值
+	len=N          字符串按字符数,切片和map按长度,数字按数值
+	oneof=a b c    取值必须是列出的某一项
+	email          合法的邮箱地址
+	regex=expr     匹配正则表达式,由于正则中可能包含逗号,regex必须是最后一条规则
+	除了required以外,字段为零值时跳过其余规则
+*/
+
+// FieldError 单个字段的校验错误
+type FieldError struct {
+	Field string `json:"field"`
+	Rule  string `json:"rule"`
+	Param string `json:"param,omitempty"`
+	Msg   string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Msg
+}
+
+// ValidationErrors 所有校验失败的字段
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Error())
+	}
+	return "mygee: validation failed: " + strings.Join(msgs, "; ")
+}
+
+// regexCache 缓存编译好的正则,避免每次校验都重新编译
+var regexCache sync.Map
+
+// Validate
+// 按validate tag校验结构体,有字段不满足时返回ValidationErrors
+func Validate(obj interface{}) error {
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs ValidationErrors
+	validateStruct(v, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateStruct(v reflect.Value, prefix string, errs *ValidationErrors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" && !sf.Anonymous {
+			continue
+		}
+		field := v.Field(i)
+		name := prefix + fieldName(sf)
+		if sf.Anonymous {
+			name = strings.TrimSuffix(prefix, ".")
+		}
+
+		if rules := sf.Tag.Get("validate"); rules != "" && rules != "-" {
+			validateField(field, name, rules, errs)
+		}
+		validateNested(field, name, errs)
+	}
+}
+
+// validateNested 递归校验结构体字段以及结构体切片中的元素
+func validateNested(v reflect.Value, name string, errs *ValidationErrors) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == timeType {
+			return
+		}
+		if name != "" {
+			name += "."
+		}
+		validateStruct(v, name, errs)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateNested(v.Index(i), fmt.Sprintf("%s[%d]", name, i), errs)
+		}
+	}
+}
+
+// fieldName 错误中展示的字段名,优先使用请求里的名字
+func fieldName(sf reflect.StructField) string {
+	for _, tag := range []string{"json", "form", "query", "uri"} {
+		if name := tagName(sf, tag); name != "" && name != "-" {
+			return name
+		}
+	}
+	return sf.Name
+}
+
+func validateField(v reflect.Value, name string, rules string, errs *ValidationErrors) {
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+
+	for rules != "" {
+		var key, param string
+		key, param, rules = nextRule(rules)
+		if key != "required" && isZero(v) {
+			continue
+		}
+		if msg := checkRule(v, key, param); msg != "" {
+			*errs = append(*errs, FieldError{Field: name, Rule: key, Param: param, Msg: msg})
+		}
+	}
+}
+
+// nextRule 取出rules中的第一条规则,返回规则名、参数和剩余的规则
+func nextRule(rules string) (key, param, rest string) {
+	rule := rules
+	if strings.HasPrefix(rules, "regex=") {
+		rest = ""
+	} else if i := strings.IndexByte(rules, ','); i >= 0 {
+		rule, rest = rules[:i], rules[i+1:]
+	}
+	key = rule
+	if i := strings.IndexByte(rule, '='); i >= 0 {
+		key, param = rule[:i], rule[i+1:]
+	}
+	return key, param, rest
+}
+
+func isZero(v reflect.Value) bool {
+	if !v.IsValid() {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+// checkRule 校验通过时返回空字符串,否则返回错误描述
+func checkRule(v reflect.Value, key, param string) string {
+	switch key {
+	case "required":
+		if isZero(v) {
+			return "is required"
+		}
+	case "min", "max", "len":
+		return checkSize(v, key, param)
+	case "oneof":
+		s := fmt.Sprint(v.Interface())
+		for _, opt := range strings.Fields(param) {
+			if s == opt {
+				return ""
+			}
+		}
+		return "must be one of [" + param + "]"
+	case "email":
+		s := fmt.Sprint(v.Interface())
+		if addr, err := mail.ParseAddress(s); err != nil || addr.Address != s {
+			return "must be a valid email address"
+		}
+	case "regex":
+		re, ok := regexCache.Load(param)
+		if !ok {
+			compiled, err := regexp.Compile(param)
+			if err != nil {
+				panic(fmt.Sprintf("mygee: invalid regex %q in validate tag: %v", param, err))
+			}
+			re, _ = regexCache.LoadOrStore(param, compiled)
+		}
+		if !re.(*regexp.Regexp).MatchString(fmt.Sprint(v.Interface())) {
+			return "must match " + param
+		}
+	default:
+		panic(fmt.Sprintf("mygee: unknown validate rule %q", key))
+	}
+	return ""
+}
+
+func checkSize(v reflect.Value, key, param string) string {
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("mygee: invalid %s=%q in validate tag", key, param))
+	}
+
+	var size float64
+	unit := ""
+	switch v.Kind() {
+	case reflect.String:
+		size, unit = float64(utf8.RuneCountInString(v.String())), " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		size, unit = float64(v.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		size = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		size = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		size = v.Float()
+	default:
+		panic(fmt.Sprintf("mygee: %s is not supported on %s", key, v.Type()))
+	}
+
+	switch {
+	case key == "min" && size < limit:
+		return "must be at least " + param + unit
+	case key == "max" && size > limit:
+		return "must be at most " + param + unit
+	case key == "len" && size != limit:
+		return "must be exactly " + param + unit
+	}
+	return ""
+}
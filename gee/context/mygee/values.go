@@ -0,0 +1,128 @@
+package mygee
+
+import (
+	"fmt"
+	"time"
+)
+
+/*
+	请求范围内的键值存储,以及context.Context接口的实现
+	Context的Deadline/Done/Err委托给Req.Context(),所以可以直接把c传给数据库、rpc等调用,
+	请求被取消或超时时这些调用会随之结束
+*/
+
+// Set 保存一个键值对,第一次调用时才创建Keys
+func (c *Context) Set(key string, value interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.Keys == nil {
+		c.Keys = make(map[string]interface{})
+	}
+	c.Keys[key] = value
+}
+
+func (c *Context) Get(key string) (value interface{}, exists bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	value, exists = c.Keys[key]
+	return
+}
+
+// MustGet 键不存在时panic
+func (c *Context) MustGet(key string) interface{} {
+	if value, exists := c.Get(key); exists {
+		return value
+	}
+	panic(fmt.Sprintf("mygee: key %q does not exist", key))
+}
+
+func (c *Context) GetString(key string) (s string) {
+	if val, ok := c.Get(key); ok && val != nil {
+		s, _ = val.(string)
+	}
+	return
+}
+
+func (c *Context) GetBool(key string) (b bool) {
+	if val, ok := c.Get(key); ok && val != nil {
+		b, _ = val.(bool)
+	}
+	return
+}
+
+func (c *Context) GetInt(key string) (i int) {
+	if val, ok := c.Get(key); ok && val != nil {
+		i, _ = val.(int)
+	}
+	return
+}
+
+func (c *Context) GetInt64(key string) (i int64) {
+	if val, ok := c.Get(key); ok && val != nil {
+		i, _ = val.(int64)
+	}
+	return
+}
+
+func (c *Context) GetFloat64(key string) (f float64) {
+	if val, ok := c.Get(key); ok && val != nil {
+		f, _ = val.(float64)
+	}
+	return
+}
+
+func (c *Context) GetDuration(key string) (d time.Duration) {
+	if val, ok := c.Get(key); ok && val != nil {
+		d, _ = val.(time.Duration)
+	}
+	return
+}
+
+func (c *Context) GetStringSlice(key string) (ss []string) {
+	if val, ok := c.Get(key); ok && val != nil {
+		ss, _ = val.([]string)
+	}
+	return
+}
+
+// resetKeys 清空Keys但保留map,复用时不需要重新分配
+func (c *Context) resetKeys() {
+	for k := range c.Keys {
+		delete(c.Keys, k)
+	}
+}
+
+func (c *Context) copyKeys() map[string]interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.Keys == nil {
+		return nil
+	}
+	keys := make(map[string]interface{}, len(c.Keys))
+	for k, v := range c.Keys {
+		keys[k] = v
+	}
+	return keys
+}
+
+func (c *Context) Deadline() (deadline time.Time, ok bool) {
+	return c.Req.Context().Deadline()
+}
+
+func (c *Context) Done() <-chan struct{} {
+	return c.Req.Context().Done()
+}
+
+func (c *Context) Err() error {
+	return c.Req.Context().Err()
+}
+
+// Value 字符串类型的key先在Keys中查找,找不到时再交给Req.Context()
+func (c *Context) Value(key interface{}) interface{} {
+	if k, ok := key.(string); ok {
+		if val, exists := c.Get(k); exists {
+			return val
+		}
+	}
+	return c.Req.Context().Value(key)
+}
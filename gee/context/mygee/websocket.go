@@ -0,0 +1,542 @@
+package mygee
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+/*
+	基于RFC 6455的WebSocket,只依赖标准库
+	c.Upgrade完成握手后通过http.Hijacker接管连接,返回的WSConn按消息读写:
+	分片的消息在ReadMessage中拼接完整后返回,ping自动回复pong,收到close帧时回复close并关闭连接
+	握手请求和普通请求一样经过路由和分组中间件,所以认证、限流等中间件对WebSocket同样生效
+*/
+
+// WebSocket消息类型,即帧的opcode
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+
+	continuationFrame = 0
+)
+
+// 关闭帧的状态码
+const (
+	CloseNormalClosure      = 1000
+	CloseGoingAway          = 1001
+	CloseProtocolError      = 1002
+	CloseUnsupportedData    = 1003
+	CloseNoStatusReceived   = 1005
+	CloseInvalidPayloadData = 1007
+	ClosePolicyViolation    = 1008
+	CloseMessageTooBig      = 1009
+	CloseInternalServerErr  = 1011
+)
+
+const (
+	wsGUID                = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	defaultMaxMessageSize = 1 << 20
+	maxControlPayload     = 125
+	closeTimeout          = time.Second
+)
+
+var (
+	ErrWSClosed          = errors.New("mygee: websocket connection closed")
+	errBadHandshake      = errors.New("bad websocket handshake")
+	errOriginNotAllowed  = errors.New("websocket origin not allowed")
+	errWSVersion         = errors.New("unsupported websocket version")
+	errInvalidWSMessage  = errors.New("mygee: invalid websocket message type")
+	errControlTooLarge   = errors.New("mygee: websocket control frame payload too large")
+	errWSNotHijackable   = errors.New("mygee: websocket response writer does not support hijacking")
+	errWSProtocol        = errors.New("mygee: websocket protocol error")
+	errWSInvalidUTF8     = errors.New("mygee: websocket text message is not valid utf-8")
+	errWSMessageTooLarge = errors.New("mygee: websocket message too large")
+)
+
+// CloseError 对端发送close帧或因协议错误关闭连接时ReadMessage返回的错误
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("mygee: websocket close %d %s", e.Code, e.Text)
+}
+
+// WSConfig 握手和连接的配置
+type WSConfig struct {
+	// MaxMessageSize 单条消息(拼接所有分片后)的最大字节数,默认为1MB
+	MaxMessageSize int64
+	// CheckOrigin 校验Origin请求头,默认只允许与Host相同的来源或没有Origin的请求
+	CheckOrigin func(r *http.Request) bool
+	// Subprotocols 服务端支持的子协议,按客户端给出的顺序选择第一个支持的
+	Subprotocols []string
+}
+
+// WSHandler 处理已经完成握手的WebSocket连接,返回后连接会被关闭
+type WSHandler func(c *Context, ws *WSConn)
+
+// WS
+// 注册WebSocket处理函数,握手请求为GET,经过分组的中间件后再升级
+func (g *RouterGroup) WS(pattern string, handler WSHandler) *Route {
+	return g.GET(pattern, func(c *Context) {
+		ws, err := c.Upgrade()
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		handler(c, ws)
+	})
+}
+
+// Upgrade 使用默认配置升级为WebSocket连接
+func (c *Context) Upgrade() (*WSConn, error) {
+	return c.UpgradeWithConfig(WSConfig{})
+}
+
+// UpgradeWithConfig
+// 校验握手请求并接管连接,失败时以4xx终止请求并返回错误
+// 中间件设置的响应头(例如Set-Cookie)会随101响应一起发送
+func (c *Context) UpgradeWithConfig(conf WSConfig) (*WSConn, error) {
+	if conf.MaxMessageSize <= 0 {
+		conf.MaxMessageSize = defaultMaxMessageSize
+	}
+	if conf.CheckOrigin == nil {
+		conf.CheckOrigin = sameOrigin
+	}
+
+	r := c.Req
+	if r.Method != http.MethodGet ||
+		!headerContainsToken(r.Header, "Connection", "upgrade") ||
+		!headerContainsToken(r.Header, "Upgrade", "websocket") {
+		c.AbortWithError(http.StatusBadRequest, errBadHandshake).SetType(ErrorTypePublic)
+		return nil, errBadHandshake
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		c.SetHeader("Sec-WebSocket-Version", "13")
+		c.AbortWithError(http.StatusUpgradeRequired, errWSVersion).SetType(ErrorTypePublic)
+		return nil, errWSVersion
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		c.AbortWithError(http.StatusBadRequest, errBadHandshake).SetType(ErrorTypePublic)
+		return nil, errBadHandshake
+	}
+	if !conf.CheckOrigin(r) {
+		c.AbortWithError(http.StatusForbidden, errOriginNotAllowed).SetType(ErrorTypePublic)
+		return nil, errOriginNotAllowed
+	}
+	subprotocol := selectSubprotocol(r, conf.Subprotocols)
+
+	if c.W.Written() {
+		return nil, errBadHandshake
+	}
+	c.Status(http.StatusSwitchingProtocols)
+	conn, brw, err := c.W.Hijack()
+	if err != nil {
+		c.AbortWithError(http.StatusInternalServerError, errWSNotHijackable)
+		return nil, err
+	}
+	// 接管后http.Server设置的超时不再适用
+	conn.SetDeadline(time.Time{})
+
+	h := c.W.Header().Clone()
+	h.Set("Upgrade", "websocket")
+	h.Set("Connection", "Upgrade")
+	h.Set("Sec-WebSocket-Accept", acceptKey(key))
+	if subprotocol != "" {
+		h.Set("Sec-WebSocket-Protocol", subprotocol)
+	}
+	h.Del("Content-Length")
+	h.Del("Content-Type")
+
+	var buf strings.Builder
+	buf.WriteString("HTTP/1.1 101 Switching Protocols\r\n")
+	h.Write(&buf)
+	buf.WriteString("\r\n")
+	if _, err := io.WriteString(conn, buf.String()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &WSConn{
+		conn:           conn,
+		br:             brw.Reader,
+		subprotocol:    subprotocol,
+		MaxMessageSize: conf.MaxMessageSize,
+	}, nil
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// headerContainsToken 判断逗号分隔的请求头中是否包含token,忽略大小写
+func headerContainsToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, part := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+func selectSubprotocol(r *http.Request, supported []string) string {
+	for _, v := range r.Header.Values("Sec-WebSocket-Protocol") {
+		for _, proto := range strings.Split(v, ",") {
+			proto = strings.TrimSpace(proto)
+			for _, s := range supported {
+				if proto == s {
+					return s
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// WSConn
+// 一个WebSocket连接,同一时间只能有一个goroutine读,写操作可以并发
+type WSConn struct {
+	// MaxMessageSize 单条消息的最大字节数,超过时以1009关闭连接
+	MaxMessageSize int64
+	// PongHandler 收到pong帧时调用
+	PongHandler func(data []byte)
+
+	conn        net.Conn
+	br          *bufio.Reader
+	subprotocol string
+
+	mu        sync.Mutex // 保护写操作和关闭状态
+	closeSent bool
+	closed    bool
+}
+
+// Subprotocol 协商得到的子协议
+func (ws *WSConn) Subprotocol() string {
+	return ws.subprotocol
+}
+
+func (ws *WSConn) RemoteAddr() net.Addr {
+	return ws.conn.RemoteAddr()
+}
+
+func (ws *WSConn) SetReadDeadline(t time.Time) error {
+	return ws.conn.SetReadDeadline(t)
+}
+
+func (ws *WSConn) SetWriteDeadline(t time.Time) error {
+	return ws.conn.SetWriteDeadline(t)
+}
+
+// ReadMessage
+// 读取一条完整的消息,返回消息类型TextMessage或BinaryMessage
+// 对端关闭连接时返回*CloseError
+func (ws *WSConn) ReadMessage() (int, []byte, error) {
+	messageType := 0
+	var message []byte
+	for {
+		fin, opcode, payload, err := ws.readFrame(int64(len(message)))
+		if err != nil {
+			return 0, nil, ws.fail(err)
+		}
+
+		switch opcode {
+		case PingMessage:
+			if err := ws.writeFrame(PongMessage, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if ws.PongHandler != nil {
+				ws.PongHandler(payload)
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, ws.handleClose(payload)
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, ws.fail(errWSProtocol)
+			}
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, ws.fail(errWSProtocol)
+			}
+			messageType = opcode
+		default:
+			return 0, nil, ws.fail(errWSProtocol)
+		}
+
+		message = append(message, payload...)
+		if !fin {
+			continue
+		}
+		if messageType == TextMessage && !utf8.Valid(message) {
+			return 0, nil, ws.fail(errWSInvalidUTF8)
+		}
+		if message == nil {
+			message = []byte{}
+		}
+		return messageType, message, nil
+	}
+}
+
+// readFrame 读取一帧并去掉掩码,read为当前消息已经读取的字节数
+func (ws *WSConn) readFrame(read int64) (bool, int, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(ws.br, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin := header[0]&0x80 != 0
+	opcode := int(header[0] & 0x0f)
+	// 没有协商扩展时RSV位必须为0,客户端发送的帧必须带掩码
+	if header[0]&0x70 != 0 || header[1]&0x80 == 0 {
+		return false, 0, nil, errWSProtocol
+	}
+
+	length := int64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(ws.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(ws.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		if ext[0]&0x80 != 0 {
+			return false, 0, nil, errWSProtocol
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+
+	if opcode >= CloseMessage {
+		if !fin || length > maxControlPayload {
+			return false, 0, nil, errWSProtocol
+		}
+	} else if length > ws.MaxMessageSize-read {
+		// 不能写成read+length,对端声明的63位长度会使加法溢出
+		return false, 0, nil, errWSMessageTooLarge
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(ws.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(ws.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i&3]
+	}
+	return fin, opcode, payload, nil
+}
+
+// handleClose 回复对端的close帧并关闭连接
+func (ws *WSConn) handleClose(payload []byte) error {
+	code, text := CloseNoStatusReceived, ""
+	switch {
+	case len(payload) == 1:
+		return ws.fail(errWSProtocol)
+	case len(payload) >= 2:
+		code = int(binary.BigEndian.Uint16(payload))
+		text = string(payload[2:])
+		if !validCloseCode(code) {
+			return ws.fail(errWSProtocol)
+		}
+		if !utf8.ValidString(text) {
+			return ws.fail(errWSInvalidUTF8)
+		}
+	}
+
+	reply := code
+	if reply == CloseNoStatusReceived {
+		reply = CloseNormalClosure
+	}
+	ws.sendClose(reply, "")
+	ws.closeConn()
+	return &CloseError{Code: code, Text: text}
+}
+
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1014:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+// fail 因为读取错误或协议错误关闭连接
+func (ws *WSConn) fail(err error) error {
+	var code int
+	switch err {
+	case errWSProtocol:
+		code = CloseProtocolError
+	case errWSInvalidUTF8:
+		code = CloseInvalidPayloadData
+	case errWSMessageTooLarge:
+		code = CloseMessageTooBig
+	default:
+		// 网络错误,或者已经发送过close帧后对端直接断开
+		closing := ws.isClosed()
+		ws.closeConn()
+		if closing {
+			return ErrWSClosed
+		}
+		return err
+	}
+	ws.sendClose(code, err.Error())
+	ws.closeConn()
+	return &CloseError{Code: code, Text: err.Error()}
+}
+
+// WriteMessage 以单个帧写出一条消息
+func (ws *WSConn) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case TextMessage, BinaryMessage:
+	case PingMessage, PongMessage:
+		if len(data) > maxControlPayload {
+			return errControlTooLarge
+		}
+	default:
+		return errInvalidWSMessage
+	}
+	return ws.writeFrame(messageType, data)
+}
+
+func (ws *WSConn) WriteText(text string) error {
+	return ws.WriteMessage(TextMessage, []byte(text))
+}
+
+// Ping 发送ping帧,对端的pong由PongHandler处理
+func (ws *WSConn) Ping(data []byte) error {
+	return ws.WriteMessage(PingMessage, data)
+}
+
+func (ws *WSConn) writeFrame(opcode int, data []byte) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.closeSent || ws.closed {
+		return ErrWSClosed
+	}
+	return ws.writeFrameLocked(opcode, data)
+}
+
+// writeFrameLocked 服务端发送的帧不带掩码
+func (ws *WSConn) writeFrameLocked(opcode int, data []byte) error {
+	header := make([]byte, 2, 10+len(data))
+	header[0] = 0x80 | byte(opcode)
+	switch n := len(data); {
+	case n <= maxControlPayload:
+		header[1] = byte(n)
+	case n <= 0xffff:
+		header[1] = 126
+		header = append(header, byte(n>>8), byte(n))
+	default:
+		header[1] = 127
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(n))
+		header = append(header, ext[:]...)
+	}
+	_, err := ws.conn.Write(append(header, data...))
+	return err
+}
+
+// sendClose 发送close帧,只发送一次
+func (ws *WSConn) sendClose(code int, text string) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.closeSent || ws.closed {
+		return nil
+	}
+	ws.closeSent = true
+	if len(text) > maxControlPayload-2 {
+		text = text[:maxControlPayload-2]
+	}
+	payload := make([]byte, 2, 2+len(text))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, text...)
+	ws.conn.SetWriteDeadline(time.Now().Add(closeTimeout))
+	return ws.writeFrameLocked(CloseMessage, payload)
+}
+
+func (ws *WSConn) closeConn() {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if !ws.closed {
+		ws.closed = true
+		ws.conn.Close()
+	}
+}
+
+func (ws *WSConn) isClosed() bool {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.closeSent || ws.closed
+}
+
+// CloseWithCode
+// 发送close帧开始关闭握手,对端回复的close帧由ReadMessage处理,
+// 之后ReadMessage返回*CloseError,连接随之关闭
+func (ws *WSConn) CloseWithCode(code int, text string) error {
+	return ws.sendClose(code, text)
+}
+
+// Close
+// 发送1000 close帧(如果还没有发送过),并在最多1秒内等待对端回复后关闭连接
+// 只能在没有其他goroutine正在ReadMessage时调用
+func (ws *WSConn) Close() error {
+	ws.mu.Lock()
+	closed := ws.closed
+	ws.mu.Unlock()
+	if closed {
+		return nil
+	}
+
+	ws.sendClose(CloseNormalClosure, "")
+	ws.conn.SetReadDeadline(time.Now().Add(closeTimeout))
+	for {
+		if _, _, err := ws.ReadMessage(); err != nil {
+			break
+		}
+	}
+	ws.closeConn()
+	return nil
+}
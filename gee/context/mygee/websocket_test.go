@@ -0,0 +1,248 @@
+package mygee
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// wsClient 测试用的最小WebSocket客户端,发送的帧带掩码
+type wsClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dialWS(t *testing.T, srv *httptest.Server, path string) (*wsClient, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+path, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Sec-WebSocket-Protocol", "chat, superchat")
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &wsClient{conn: conn, br: br}, resp
+}
+
+func (c *wsClient) writeFrame(fin bool, opcode byte, payload []byte) {
+	b0 := opcode
+	if fin {
+		b0 |= 0x80
+	}
+	frame := []byte{b0}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, 0x80|byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 0x80|126, byte(n>>8), byte(n))
+	default:
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(n))
+		frame = append(append(frame, 0x80|127), ext[:]...)
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i&3])
+	}
+	c.conn.Write(frame)
+}
+
+func (c *wsClient) readFrame(t *testing.T) (byte, []byte) {
+	t.Helper()
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		t.Fatalf("read frame: %v", err)
+	}
+	if header[1]&0x80 != 0 {
+		t.Fatal("server frames must not be masked")
+	}
+	n := int(header[1] & 0x7f)
+	if n == 126 {
+		var ext [2]byte
+		io.ReadFull(c.br, ext[:])
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, n)
+	io.ReadFull(c.br, payload)
+	return header[0] & 0x0f, payload
+}
+
+func closePayload(code int, text string) []byte {
+	p := make([]byte, 2, 2+len(text))
+	binary.BigEndian.PutUint16(p, uint16(code))
+	return append(p, text...)
+}
+
+func wsEngine(t *testing.T, closed chan error) *httptest.Server {
+	r := New()
+	api := r.Group("/api")
+	api.Use(func(c *Context) {
+		c.SetHeader("X-Group", "api")
+		c.Next()
+	})
+	api.GET("/chat", func(c *Context) {
+		ws, err := c.UpgradeWithConfig(WSConfig{MaxMessageSize: 1000, Subprotocols: []string{"superchat"}})
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		for {
+			typ, msg, err := ws.ReadMessage()
+			if err != nil {
+				closed <- err
+				return
+			}
+			ws.WriteMessage(typ, msg)
+		}
+	})
+	r.WS("/echo", func(c *Context, ws *WSConn) {
+		ws.WriteText("hello " + c.Query("name"))
+	})
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestWebSocketEcho(t *testing.T) {
+	closed := make(chan error, 1)
+	srv := wsEngine(t, closed)
+	client, resp := dialWS(t, srv, "/api/chat")
+	defer client.conn.Close()
+
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" ||
+		resp.Header.Get("Sec-WebSocket-Protocol") != "superchat" ||
+		resp.Header.Get("X-Group") != "api" {
+		t.Fatalf("unexpected handshake response %d %v", resp.StatusCode, resp.Header)
+	}
+
+	client.writeFrame(true, TextMessage, []byte("hi"))
+	if op, msg := client.readFrame(t); op != TextMessage || string(msg) != "hi" {
+		t.Fatalf("unexpected echo %d %q", op, msg)
+	}
+
+	// 分片消息中间穿插ping
+	client.writeFrame(false, BinaryMessage, []byte("ab"))
+	client.writeFrame(true, PingMessage, []byte("p"))
+	client.writeFrame(true, continuationFrame, []byte("cd"))
+	if op, msg := client.readFrame(t); op != PongMessage || string(msg) != "p" {
+		t.Fatalf("expected pong, got %d %q", op, msg)
+	}
+	if op, msg := client.readFrame(t); op != BinaryMessage || string(msg) != "abcd" {
+		t.Fatalf("fragmented message should be reassembled, got %d %q", op, msg)
+	}
+
+	client.writeFrame(true, CloseMessage, closePayload(CloseGoingAway, "bye"))
+	if op, msg := client.readFrame(t); op != CloseMessage || binary.BigEndian.Uint16(msg) != CloseGoingAway {
+		t.Fatalf("close should be echoed, got %d %v", op, msg)
+	}
+	var ce *CloseError
+	if err := <-closed; !errors.As(err, &ce) || ce.Code != CloseGoingAway || ce.Text != "bye" {
+		t.Fatalf("handler should see the close error, got %v", err)
+	}
+}
+
+func TestWebSocketProtocolErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		send func(c *wsClient)
+		code int
+	}{
+		{"too large", func(c *wsClient) { c.writeFrame(true, BinaryMessage, make([]byte, 1001)) }, CloseMessageTooBig},
+		{"fragments too large", func(c *wsClient) {
+			c.writeFrame(false, TextMessage, make([]byte, 600))
+			c.writeFrame(true, continuationFrame, make([]byte, 600))
+		}, CloseMessageTooBig},
+		{"fragment declares huge length", func(c *wsClient) {
+			c.writeFrame(false, TextMessage, make([]byte, 600))
+			// 只发送帧头,长度为最大的63位整数
+			c.conn.Write([]byte{0x80, 0x80 | 127, 0x7f, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+		}, CloseMessageTooBig},
+		{"invalid utf8", func(c *wsClient) { c.writeFrame(true, TextMessage, []byte{0xff, 0xfe}) }, CloseInvalidPayloadData},
+		{"orphan continuation", func(c *wsClient) { c.writeFrame(true, continuationFrame, []byte("x")) }, CloseProtocolError},
+		{"fragmented ping", func(c *wsClient) { c.writeFrame(false, PingMessage, nil) }, CloseProtocolError},
+		{"unmasked", func(c *wsClient) { c.conn.Write([]byte{0x81, 0x01, 'x'}) }, CloseProtocolError},
+		{"reserved opcode", func(c *wsClient) { c.writeFrame(true, 3, nil) }, CloseProtocolError},
+	}
+	for _, tc := range testCases {
+		closed := make(chan error, 1)
+		client, _ := dialWS(t, wsEngine(t, closed), "/api/chat")
+		tc.send(client)
+		op, msg := client.readFrame(t)
+		if op != CloseMessage || int(binary.BigEndian.Uint16(msg)) != tc.code {
+			t.Errorf("%s: expected close %d, got %d %v", tc.name, tc.code, op, msg)
+		}
+		<-closed
+		client.conn.Close()
+	}
+}
+
+func TestWebSocketRegister(t *testing.T) {
+	srv := wsEngine(t, make(chan error, 1))
+	client, resp := dialWS(t, srv, "/echo?name=gee")
+	defer client.conn.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Protocol") != "" {
+		t.Fatalf("unexpected handshake response %d %v", resp.StatusCode, resp.Header)
+	}
+	if op, msg := client.readFrame(t); op != TextMessage || string(msg) != "hello gee" {
+		t.Fatalf("unexpected message %d %q", op, msg)
+	}
+	// 处理函数返回后服务端发起关闭握手
+	if op, msg := client.readFrame(t); op != CloseMessage || binary.BigEndian.Uint16(msg) != CloseNormalClosure {
+		t.Fatalf("expected close frame, got %d %v", op, msg)
+	}
+	client.writeFrame(true, CloseMessage, closePayload(CloseNormalClosure, ""))
+	if _, err := client.br.ReadByte(); err != io.EOF {
+		t.Fatalf("server should close the connection, got %v", err)
+	}
+}
+
+func TestWebSocketBadHandshake(t *testing.T) {
+	r := New()
+	r.WS("/ws", func(c *Context, ws *WSConn) {})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ws", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("plain GET should be rejected with 400, got %d", w.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	req.Header.Set("Connection", "keep-alive, Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "8")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusUpgradeRequired || w.Header().Get("Sec-WebSocket-Version") != "13" {
+		t.Fatalf("unsupported version should get 426, got %d %v", w.Code, w.Header())
+	}
+
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	req.Header.Set("Origin", "http://evil.example")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("cross origin handshake should get 403, got %d", w.Code)
+	}
+}